@@ -0,0 +1,67 @@
+package bilibili
+
+import (
+	"fmt"
+	"sync"
+)
+
+// ArticleInfo holds the fields we extract from the article (专栏) view info API.
+type ArticleInfo struct {
+	ID         int64
+	Title      string
+	Covers     []string
+	AuthorMid  int64
+	AuthorName string
+}
+
+var (
+	articleCache   = make(map[int64]*ArticleInfo)
+	articleCacheMu sync.RWMutex
+)
+
+const biliArticleAPI = "https://api.bilibili.com/x/article/viewinfo"
+
+// FetchArticleInfo queries Bilibili's public API for article metadata.
+// Results are cached in memory to avoid repeated requests.
+func FetchArticleInfo(cvid int64) (*ArticleInfo, error) {
+	articleCacheMu.RLock()
+	if info, ok := articleCache[cvid]; ok {
+		articleCacheMu.RUnlock()
+		return info, nil
+	}
+	articleCacheMu.RUnlock()
+
+	var data struct {
+		Title           string   `json:"title"`
+		Mid             int64    `json:"mid"`
+		AuthorName      string   `json:"author_name"`
+		BannerURL       string   `json:"banner_url"`
+		OriginImageURLs []string `json:"origin_image_urls"`
+		ImageURLs       []string `json:"image_urls"`
+	}
+	if err := getJSON(fmt.Sprintf("%s?id=%d", biliArticleAPI, cvid), &data); err != nil {
+		return nil, err
+	}
+
+	covers := data.OriginImageURLs
+	if len(covers) == 0 {
+		covers = data.ImageURLs
+	}
+	if len(covers) == 0 && data.BannerURL != "" {
+		covers = []string{data.BannerURL}
+	}
+
+	info := &ArticleInfo{
+		ID:         cvid,
+		Title:      data.Title,
+		Covers:     covers,
+		AuthorMid:  data.Mid,
+		AuthorName: data.AuthorName,
+	}
+
+	articleCacheMu.Lock()
+	articleCache[cvid] = info
+	articleCacheMu.Unlock()
+
+	return info, nil
+}
@@ -0,0 +1,75 @@
+package bilibili
+
+import (
+	"fmt"
+	"sync"
+)
+
+// AudioInfo holds the fields we extract for an audio track (音频).
+type AudioInfo struct {
+	ID         int64
+	Title      string
+	Cover      string
+	Intro      string
+	Duration   int
+	Pubtime    int64
+	Play       int64
+	AuthorMid  int64
+	AuthorName string
+}
+
+var (
+	audioCache   = make(map[int64]*AudioInfo)
+	audioCacheMu sync.RWMutex
+)
+
+const biliAudioInfoAPI = "https://www.bilibili.com/audio/music-service-c/web/song/info"
+
+// FetchAudioInfo queries Bilibili's music service for an audio track.
+// Results are cached in memory to avoid repeated requests.
+func FetchAudioInfo(sid int64) (*AudioInfo, error) {
+	audioCacheMu.RLock()
+	if info, ok := audioCache[sid]; ok {
+		audioCacheMu.RUnlock()
+		return info, nil
+	}
+	audioCacheMu.RUnlock()
+
+	var data struct {
+		ID        int64  `json:"id"`
+		Title     string `json:"title"`
+		Cover     string `json:"cover"`
+		Intro     string `json:"intro"`
+		Duration  int    `json:"duration"`
+		Passtime  int64  `json:"passtime"`
+		UID       int64  `json:"uid"`
+		Uname     string `json:"uname"`
+		Statistic struct {
+			Play int64 `json:"play"`
+		} `json:"statistic"`
+	}
+	if err := getJSON(fmt.Sprintf("%s?sid=%d", biliAudioInfoAPI, sid), &data); err != nil {
+		return nil, err
+	}
+	if data.ID == 0 {
+		return nil, fmt.Errorf("audio %d not found", sid)
+	}
+
+	info := &AudioInfo{
+		ID:         data.ID,
+		Title:      data.Title,
+		Cover:      data.Cover,
+		Intro:      data.Intro,
+		Duration:   data.Duration,
+		Pubtime:    data.Passtime,
+		Play:       data.Statistic.Play,
+		AuthorMid:  data.UID,
+		AuthorName: data.Uname,
+	}
+
+	audioCacheMu.Lock()
+	audioCache[sid] = info
+	audioCacheMu.Unlock()
+
+	return info, nil
+}
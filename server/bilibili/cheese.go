@@ -0,0 +1,94 @@
+package bilibili
+
+import (
+	"fmt"
+	"sync"
+)
+
+// CheeseEpisodeInfo holds the fields we extract for one course (课程) episode
+// from the pugv season API, together with its season and owner.
+type CheeseEpisodeInfo struct {
+	SeasonID    int64
+	SeasonTitle string
+	SeasonCover string
+	Epid        int64
+	Aid         int64
+	Cid         int64
+	Title       string
+	Cover       string
+	Duration    int
+	Index       int
+	Total       int
+	OwnerMid    int64
+	OwnerName   string
+	OwnerFace   string
+}
+
+var (
+	cheeseCache   = make(map[int64]*CheeseEpisodeInfo)
+	cheeseCacheMu sync.RWMutex
+)
+
+const biliCheeseSeasonAPI = "https://api.bilibili.com/pugv/view/web/season"
+
+// FetchCheeseEpisode queries Bilibili's pugv API for the season containing
+// epid and returns that episode's metadata. Every episode of the season is
+// cached so later episodes of the same course don't need another request.
+func FetchCheeseEpisode(epid int64) (*CheeseEpisodeInfo, error) {
+	cheeseCacheMu.RLock()
+	if info, ok := cheeseCache[epid]; ok {
+		cheeseCacheMu.RUnlock()
+		return info, nil
+	}
+	cheeseCacheMu.RUnlock()
+
+	var data struct {
+		SeasonID int64  `json:"season_id"`
+		Title    string `json:"title"`
+		Cover    string `json:"cover"`
+		UpInfo   struct {
+			Mid    int64  `json:"mid"`
+			Uname  string `json:"uname"`
+			Avatar string `json:"avatar"`
+		} `json:"up_info"`
+		Episodes []struct {
+			ID       int64  `json:"id"`
+			Aid      int64  `json:"aid"`
+			Cid      int64  `json:"cid"`
+			Title    string `json:"title"`
+			Cover    string `json:"cover"`
+			Duration int    `json:"duration"`
+			Index    int    `json:"index"`
+		} `json:"episodes"`
+	}
+	if err := getJSON(fmt.Sprintf("%s?ep_id=%d", biliCheeseSeasonAPI, epid), &data); err != nil {
+		return nil, err
+	}
+
+	cheeseCacheMu.Lock()
+	defer cheeseCacheMu.Unlock()
+	for _, ep := range data.Episodes {
+		cheeseCache[ep.ID] = &CheeseEpisodeInfo{
+			SeasonID:    data.SeasonID,
+			SeasonTitle: data.Title,
+			SeasonCover: data.Cover,
+			Epid:        ep.ID,
+			Aid:         ep.Aid,
+			Cid:         ep.Cid,
+			Title:       ep.Title,
+			Cover:       ep.Cover,
+			Duration:    ep.Duration,
+			Index:       ep.Index,
+			Total:       len(data.Episodes),
+			OwnerMid:    data.UpInfo.Mid,
+			OwnerName:   data.UpInfo.Uname,
+			OwnerFace:   data.UpInfo.Avatar,
+		}
+	}
+
+	info, ok := cheeseCache[epid]
+	if !ok {
+		return nil, fmt.Errorf("episode %d not found in season %d", epid, data.SeasonID)
+	}
+	return info, nil
+}
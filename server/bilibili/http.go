@@ -0,0 +1,66 @@
+package bilibili
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"time"
+)
+
+const webUA = "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36"
+
+// APIError is a non-zero code in a Bilibili response envelope.
+type APIError struct {
+	Code    int
+	Message string
+}
+
+func (e *APIError) Error() string {
+	return fmt.Sprintf("bilibili API error: code=%d msg=%s", e.Code, e.Message)
+}
+
+// getJSON performs a GET against a Bilibili web API and decodes the "data"
+// field of its {code, message, data} envelope into out. PGC APIs put the
+// payload under "result" instead, which is used when "data" is absent.
+func getJSON(reqURL string, out interface{}) error {
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("User-Agent", webUA)
+	req.Header.Set("Referer", "https://www.bilibili.com")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return fmt.Errorf("bilibili API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return fmt.Errorf("failed to read response: %w", err)
+	}
+
+	var result struct {
+		Code    int             `json:"code"`
+		Message string          `json:"message"`
+		Data    json.RawMessage `json:"data"`
+		Result  json.RawMessage `json:"result"`
+	}
+	if err := json.Unmarshal(body, &result); err != nil {
+		return fmt.Errorf("failed to parse response: %w", err)
+	}
+	if result.Code != 0 {
+		return &APIError{Code: result.Code, Message: result.Message}
+	}
+	payload := result.Data
+	if len(payload) == 0 || string(payload) == "null" {
+		payload = result.Result
+	}
+	if err := json.Unmarshal(payload, out); err != nil {
+		return fmt.Errorf("failed to parse response data: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,83 @@
+package bilibili
+
+import (
+	"fmt"
+	"sync"
+	"time"
+)
+
+// LiveRoomInfo holds the fields we extract from the live room info API.
+type LiveRoomInfo struct {
+	RoomID     int64
+	Title      string
+	Cover      string
+	LiveStatus int // 0: offline, 1: live, 2: rotating replays
+	AreaName   string
+	OwnerMid   int64
+	OwnerName  string
+	OwnerFace  string
+}
+
+type liveCacheEntry struct {
+	info *LiveRoomInfo
+	ts   time.Time
+}
+
+var (
+	liveCache    = make(map[int64]*liveCacheEntry)
+	liveCacheMu  sync.RWMutex
+	liveCacheTTL = 5 * time.Minute // live_status changes often, keep this short
+)
+
+const biliLiveRoomAPI = "https://api.live.bilibili.com/xlive/web-room/v1/index/getInfoByRoom"
+
+// FetchLiveRoomInfo queries Bilibili's live API for room metadata and the
+// anchor's profile. Results are cached briefly since live status changes.
+func FetchLiveRoomInfo(roomID int64) (*LiveRoomInfo, error) {
+	liveCacheMu.RLock()
+	if entry, ok := liveCache[roomID]; ok && time.Since(entry.ts) < liveCacheTTL {
+		liveCacheMu.RUnlock()
+		return entry.info, nil
+	}
+	liveCacheMu.RUnlock()
+
+	var data struct {
+		RoomInfo struct {
+			RoomID     int64  `json:"room_id"`
+			UID        int64  `json:"uid"`
+			Title      string `json:"title"`
+			Cover      string `json:"cover"`
+			LiveStatus int    `json:"live_status"`
+			AreaName   string `json:"area_name"`
+		} `json:"room_info"`
+		AnchorInfo struct {
+			BaseInfo struct {
+				Uname string `json:"uname"`
+				Face  string `json:"face"`
+			} `json:"base_info"`
+		} `json:"anchor_info"`
+	}
+	if err := getJSON(fmt.Sprintf("%s?room_id=%d", biliLiveRoomAPI, roomID), &data); err != nil {
+		return nil, err
+	}
+
+	info := &LiveRoomInfo{
+		RoomID:     data.RoomInfo.RoomID,
+		Title:      data.RoomInfo.Title,
+		Cover:      data.RoomInfo.Cover,
+		LiveStatus: data.RoomInfo.LiveStatus,
+		AreaName:   data.RoomInfo.AreaName,
+		OwnerMid:   data.RoomInfo.UID,
+		OwnerName:  data.AnchorInfo.BaseInfo.Uname,
+		OwnerFace:  data.AnchorInfo.BaseInfo.Face,
+	}
+	if info.RoomID == 0 {
+		info.RoomID = roomID
+	}
+
+	liveCacheMu.Lock()
+	liveCache[roomID] = &liveCacheEntry{info: info, ts: time.Now()}
+	liveCacheMu.Unlock()
+
+	return info, nil
+}
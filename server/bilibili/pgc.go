@@ -0,0 +1,109 @@
+package bilibili
+
+import (
+	"fmt"
+	"sync"
+)
+
+// PgcEpisodeInfo holds the fields we extract for one bangumi/film episode
+// (OGV) from the PGC season API, together with its season.
+type PgcEpisodeInfo struct {
+	SeasonID    int64
+	SeasonTitle string
+	SeasonCover string
+	SeasonType  int
+	TypeName    string
+	Evaluate    string
+	Epid        int64
+	Aid         int64
+	Bvid        string
+	Cid         int64
+	Title       string // episode number, e.g. "1"
+	LongTitle   string
+	Cover       string
+	Duration    int // seconds
+	Pubtime     int64
+	Views       int64
+}
+
+var (
+	pgcCache   = make(map[int64]*PgcEpisodeInfo)
+	pgcCacheMu sync.RWMutex
+)
+
+const biliPgcSeasonAPI = "https://api.bilibili.com/pgc/view/web/season"
+
+// pgcTypeNames maps PGC season types to their display names.
+var pgcTypeNames = map[int]string{
+	1: "番剧",
+	2: "电影",
+	3: "纪录片",
+	4: "国创",
+	5: "电视剧",
+	7: "综艺",
+}
+
+// FetchPgcEpisode queries Bilibili's PGC API for the season containing epid
+// and returns that episode's metadata. Every episode of the season is cached.
+func FetchPgcEpisode(epid int64) (*PgcEpisodeInfo, error) {
+	pgcCacheMu.RLock()
+	if info, ok := pgcCache[epid]; ok {
+		pgcCacheMu.RUnlock()
+		return info, nil
+	}
+	pgcCacheMu.RUnlock()
+
+	var data struct {
+		SeasonID    int64  `json:"season_id"`
+		SeasonTitle string `json:"season_title"`
+		Cover       string `json:"cover"`
+		Type        int    `json:"type"`
+		Evaluate    string `json:"evaluate"`
+		Stat        struct {
+			Views int64 `json:"views"`
+		} `json:"stat"`
+		Episodes []struct {
+			ID        int64  `json:"id"`
+			Aid       int64  `json:"aid"`
+			Bvid      string `json:"bvid"`
+			Cid       int64  `json:"cid"`
+			Title     string `json:"title"`
+			LongTitle string `json:"long_title"`
+			Cover     string `json:"cover"`
+			Duration  int    `json:"duration"` // milliseconds
+			PubTime   int64  `json:"pub_time"`
+		} `json:"episodes"`
+	}
+	if err := getJSON(fmt.Sprintf("%s?ep_id=%d", biliPgcSeasonAPI, epid), &data); err != nil {
+		return nil, err
+	}
+
+	pgcCacheMu.Lock()
+	defer pgcCacheMu.Unlock()
+	for _, ep := range data.Episodes {
+		pgcCache[ep.ID] = &PgcEpisodeInfo{
+			SeasonID:    data.SeasonID,
+			SeasonTitle: data.SeasonTitle,
+			SeasonCover: data.Cover,
+			SeasonType:  data.Type,
+			TypeName:    pgcTypeNames[data.Type],
+			Evaluate:    data.Evaluate,
+			Epid:        ep.ID,
+			Aid:         ep.Aid,
+			Bvid:        ep.Bvid,
+			Cid:         ep.Cid,
+			Title:       ep.Title,
+			LongTitle:   ep.LongTitle,
+			Cover:       ep.Cover,
+			Duration:    ep.Duration / 1000,
+			Pubtime:     ep.PubTime,
+			Views:       data.Stat.Views,
+		}
+	}
+
+	info, ok := pgcCache[epid]
+	if !ok {
+		return nil, fmt.Errorf("episode %d not found in season %d", epid, data.SeasonID)
+	}
+	return info, nil
+}
@@ -0,0 +1,115 @@
+package bilibili
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"time"
+)
+
+// UserCard holds the profile fields we keep for followed accounts, from the
+// /x/web-interface/card API.
+type UserCard struct {
+	Mid          int64
+	Name         string
+	Face         string
+	Sign         string
+	OfficialType int // -1 none, 0 personal, 1 organization
+	OfficialDesc string
+	VipType      int // 0 none, 1 monthly, 2 annual
+	VipStatus    int
+	VipDueDate   int64 // ms
+	Deactivated  bool  // account closed (账号已注销)
+}
+
+const (
+	biliCardAPI = "https://api.bilibili.com/x/web-interface/card"
+
+	profileRefreshInterval = 12 * time.Hour
+	// Name Bilibili shows for closed accounts
+	deactivatedName = "账号已注销"
+)
+
+// FetchUserCard queries Bilibili for an account's public profile. A closed
+// account is returned with Deactivated set rather than as an error.
+func FetchUserCard(mid int64) (*UserCard, error) {
+	var data struct {
+		Card struct {
+			Name     string `json:"name"`
+			Face     string `json:"face"`
+			Sign     string `json:"sign"`
+			Official struct {
+				Type  int    `json:"type"`
+				Title string `json:"title"`
+				Desc  string `json:"desc"`
+			} `json:"Official"`
+			Vip struct {
+				Type    int   `json:"type"`
+				Status  int   `json:"status"`
+				DueDate int64 `json:"due_date"`
+			} `json:"vip"`
+		} `json:"card"`
+	}
+	err := getJSON(fmt.Sprintf("%s?mid=%d", biliCardAPI, mid), &data)
+
+	var apiErr *APIError
+	if errors.As(err, &apiErr) && (apiErr.Code == -404 || apiErr.Code == -626) {
+		return &UserCard{Mid: mid, OfficialType: -1, Deactivated: true}, nil
+	} else if err != nil {
+		return nil, err
+	}
+
+	card := &UserCard{
+		Mid:          mid,
+		Name:         data.Card.Name,
+		Face:         data.Card.Face,
+		Sign:         data.Card.Sign,
+		OfficialType: data.Card.Official.Type,
+		OfficialDesc: data.Card.Official.Title,
+		VipType:      data.Card.Vip.Type,
+		VipStatus:    data.Card.Vip.Status,
+		VipDueDate:   data.Card.Vip.DueDate,
+		Deactivated:  data.Card.Name == deactivatedName,
+	}
+	if card.OfficialDesc == "" {
+		card.OfficialDesc = data.Card.Official.Desc
+	}
+	return card, nil
+}
+
+// StartProfileRefresh launches a goroutine that periodically re-fetches the
+// profile of every followed account. getStaleMids should return the mids
+// whose stored profile is older than the given time; onFetched stores a
+// fetched card. Requests are staggered like the video refresh.
+func StartProfileRefresh(getStaleMids func(before time.Time) []int64, onFetched func(card *UserCard)) {
+	go func() {
+		refreshProfiles(getStaleMids, onFetched)
+
+		ticker := time.NewTicker(refreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			refreshProfiles(getStaleMids, onFetched)
+		}
+	}()
+}
+
+func refreshProfiles(getStaleMids func(before time.Time) []int64, onFetched func(card *UserCard)) {
+	mids := getStaleMids(time.Now().Add(-profileRefreshInterval))
+	if len(mids) == 0 {
+		return
+	}
+
+	log.Printf("[profile] refresh starting: %d accounts", len(mids))
+	fetched := 0
+	for _, mid := range mids {
+		card, err := FetchUserCard(mid)
+		if err != nil {
+			log.Printf("[profile] mid=%d: %v", mid, err)
+		} else {
+			onFetched(card)
+			fetched++
+		}
+		time.Sleep(fetchDelay)
+	}
+	log.Printf("[profile] refresh done: %d/%d accounts", fetched, len(mids))
+}
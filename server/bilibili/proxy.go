@@ -0,0 +1,247 @@
+package bilibili
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+)
+
+// proxyRule describes one anonymous read endpoint the privacy proxy may
+// forward to.
+type proxyRule struct {
+	TTL  time.Duration // how long a successful response is shared
+	Sign bool          // app API: re-sign with the server's appkey
+}
+
+// proxyHosts maps the short host names used in proxy paths to Bilibili hosts.
+var proxyHosts = map[string]string{
+	"api": "api.bilibili.com",
+	"app": "app.bilibili.com",
+}
+
+// proxyRules is the whitelist, keyed by "host/path". Only endpoints that
+// work without a login belong here; anything personalised or writing would
+// leak the server's identity across users.
+var proxyRules = map[string]proxyRule{
+	"api.bilibili.com/x/web-interface/view":            {TTL: 10 * time.Minute},
+	"api.bilibili.com/x/web-interface/view/detail":     {TTL: 10 * time.Minute},
+	"api.bilibili.com/x/web-interface/archive/stat":    {TTL: 5 * time.Minute},
+	"api.bilibili.com/x/web-interface/card":            {TTL: 30 * time.Minute},
+	"api.bilibili.com/x/web-interface/popular":         {TTL: 10 * time.Minute},
+	"api.bilibili.com/x/web-interface/ranking/v2":      {TTL: 30 * time.Minute},
+	"api.bilibili.com/x/web-interface/archive/related": {TTL: 30 * time.Minute},
+	"api.bilibili.com/x/player/pagelist":               {TTL: 30 * time.Minute},
+	"api.bilibili.com/x/tag/archive/tags":              {TTL: time.Hour},
+	"api.bilibili.com/x/space/fav/season/list":         {TTL: 10 * time.Minute},
+	"api.bilibili.com/x/article/viewinfo":              {TTL: 30 * time.Minute},
+	"api.bilibili.com/pgc/view/web/season":             {TTL: 30 * time.Minute},
+	"api.bilibili.com/pugv/view/web/season":            {TTL: 30 * time.Minute},
+	"app.bilibili.com/x/v2/space/archive/cursor":       {TTL: 10 * time.Minute, Sign: true},
+}
+
+// proxyStripParams are query parameters that identify the client device or
+// account; they are never forwarded. Signatures are dropped too, since the
+// remaining query is re-signed by the server.
+var proxyStripParams = []string{
+	"access_key", "csrf", "buvid", "buvid3", "device", "device_name",
+	"local_id", "fingerprint", "appkey", "ts", "sign", "w_rid", "wts",
+	"statistics", "trace_id",
+}
+
+// ProxyResponse is a cached upstream response.
+type ProxyResponse struct {
+	Status      int
+	ContentType string
+	Body        []byte
+	Cached      bool // served from the shared cache
+}
+
+type proxyCacheEntry struct {
+	resp    *ProxyResponse
+	expires time.Time
+}
+
+const (
+	proxyCacheMax      = 5000
+	proxyCacheMaxBytes = 64 << 20 // total body bytes kept in the cache
+	proxyMaxBody       = 2 << 20  // largest upstream response accepted
+)
+
+var (
+	proxyCache      = make(map[string]*proxyCacheEntry)
+	proxyCacheBytes int
+	proxyCacheMu    sync.Mutex
+	proxyInflight   singleflight.Group
+)
+
+// ErrProxyNotAllowed is returned for endpoints outside the whitelist.
+var ErrProxyNotAllowed = fmt.Errorf("bilibili proxy: endpoint not allowed")
+
+// ErrProxyTooLarge is returned when the upstream response exceeds proxyMaxBody.
+var ErrProxyTooLarge = fmt.Errorf("bilibili proxy: response too large")
+
+// ProxyGet forwards an anonymous GET to a whitelisted Bilibili endpoint on
+// behalf of a client. host is a short name from proxyHosts. Identifying
+// parameters are stripped, the request carries the server's own UA (and
+// app signature where needed), and successful responses are cached and
+// shared between all users.
+func ProxyGet(host, path string, query url.Values) (*ProxyResponse, error) {
+	fullHost, ok := proxyHosts[host]
+	if !ok {
+		return nil, ErrProxyNotAllowed
+	}
+	path = "/" + strings.Trim(path, "/")
+	rule, ok := proxyRules[fullHost+path]
+	if !ok {
+		return nil, ErrProxyNotAllowed
+	}
+
+	params := url.Values{}
+	for k, v := range query {
+		params[k] = v
+	}
+	for _, k := range proxyStripParams {
+		params.Del(k)
+	}
+	key := fullHost + path + "?" + canonicalQuery(params)
+
+	proxyCacheMu.Lock()
+	if e, ok := proxyCache[key]; ok && time.Now().Before(e.expires) {
+		proxyCacheMu.Unlock()
+		hit := *e.resp
+		hit.Cached = true
+		return &hit, nil
+	}
+	proxyCacheMu.Unlock()
+
+	v, err, _ := proxyInflight.Do(key, func() (interface{}, error) {
+		resp, err := proxyFetch(fullHost, path, params, rule)
+		if err != nil {
+			return nil, err
+		}
+		if resp.Status == http.StatusOK && bodyOK(resp.Body) {
+			storeProxyCache(key, resp, rule.TTL)
+		}
+		return resp, nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return v.(*ProxyResponse), nil
+}
+
+func proxyFetch(host, path string, params url.Values, rule proxyRule) (*ProxyResponse, error) {
+	ua := webUA
+	if rule.Sign {
+		ua = appUA
+		appSign(params)
+	}
+	reqURL := "https://" + host + path
+	if len(params) > 0 {
+		reqURL += "?" + params.Encode()
+	}
+
+	client := &http.Client{Timeout: 10 * time.Second}
+	req, err := http.NewRequest("GET", reqURL, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", ua)
+	req.Header.Set("Referer", "https://www.bilibili.com")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("bilibili API request failed: %w", err)
+	}
+	defer resp.Body.Close()
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, proxyMaxBody+1))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read response: %w", err)
+	}
+	if len(body) > proxyMaxBody {
+		return nil, ErrProxyTooLarge
+	}
+	return &ProxyResponse{
+		Status:      resp.StatusCode,
+		ContentType: resp.Header.Get("Content-Type"),
+		Body:        body,
+	}, nil
+}
+
+// bodyOK reports whether a response is a successful {code: 0} envelope;
+// errors such as rate limiting must not be cached for everyone.
+func bodyOK(body []byte) bool {
+	var env struct {
+		Code int `json:"code"`
+	}
+	return json.Unmarshal(body, &env) == nil && env.Code == 0
+}
+
+func storeProxyCache(key string, resp *ProxyResponse, ttl time.Duration) {
+	proxyCacheMu.Lock()
+	defer proxyCacheMu.Unlock()
+
+	now := time.Now()
+	size := len(resp.Body)
+	if old, ok := proxyCache[key]; ok {
+		dropProxyCache(key, old)
+	}
+	full := func() bool {
+		return len(proxyCache) >= proxyCacheMax || proxyCacheBytes+size > proxyCacheMaxBytes
+	}
+	if full() {
+		for k, e := range proxyCache {
+			if now.After(e.expires) {
+				dropProxyCache(k, e)
+			}
+		}
+		// Still full: drop arbitrary entries rather than grow unbounded
+		for k, e := range proxyCache {
+			if !full() {
+				break
+			}
+			dropProxyCache(k, e)
+		}
+	}
+	proxyCache[key] = &proxyCacheEntry{resp: resp, expires: now.Add(ttl)}
+	proxyCacheBytes += size
+}
+
+// dropProxyCache removes an entry and its bytes from the cache. Callers hold
+// proxyCacheMu.
+func dropProxyCache(key string, e *proxyCacheEntry) {
+	delete(proxyCache, key)
+	proxyCacheBytes -= len(e.resp.Body)
+}
+
+// canonicalQuery encodes params with sorted keys and values, so equivalent
+// requests share a cache entry.
+func canonicalQuery(params url.Values) string {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var b strings.Builder
+	for _, k := range keys {
+		vs := append([]string(nil), params[k]...)
+		sort.Strings(vs)
+		for _, v := range vs {
+			if b.Len() > 0 {
+				b.WriteByte('&')
+			}
+			b.WriteString(url.QueryEscape(k) + "=" + url.QueryEscape(v))
+		}
+	}
+	return b.String()
+}
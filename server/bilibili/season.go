@@ -0,0 +1,116 @@
+package bilibili
+
+import (
+	"fmt"
+	"time"
+)
+
+// UgcSeason holds a UGC season (合集) and its episodes, as listed by the
+// favorites season API.
+type UgcSeason struct {
+	ID         int64
+	Title      string
+	Cover      string
+	Intro      string
+	UpperMid   int64
+	UpperName  string
+	UpperFace  string
+	MediaCount int
+	Episodes   []UgcSeasonEpisode
+}
+
+// UgcSeasonEpisode is one video of a UGC season.
+type UgcSeasonEpisode struct {
+	Aid      int64
+	Bvid     string
+	Title    string
+	Cover    string
+	Duration int
+	Pubtime  int64
+	Play     int64
+	Danmaku  int64
+}
+
+const (
+	biliFavSeasonAPI = "https://api.bilibili.com/x/space/fav/season/list"
+	seasonPageSize   = 20
+	seasonMaxPages   = 50
+)
+
+// FetchUgcSeason queries Bilibili for a UGC season and all of its episodes,
+// page by page. Not cached: callers persist the result.
+func FetchUgcSeason(seasonID int64) (*UgcSeason, error) {
+	return fetchUgcSeason(seasonID, seasonMaxPages)
+}
+
+// FetchUgcSeasonInfo returns a UGC season with only its first page of
+// episodes, for when just the season's own metadata is needed.
+func FetchUgcSeasonInfo(seasonID int64) (*UgcSeason, error) {
+	return fetchUgcSeason(seasonID, 1)
+}
+
+func fetchUgcSeason(seasonID int64, maxPages int) (*UgcSeason, error) {
+	season := &UgcSeason{ID: seasonID}
+
+	for pn := 1; pn <= maxPages; pn++ {
+		var data struct {
+			Info struct {
+				ID         int64  `json:"id"`
+				Title      string `json:"title"`
+				Cover      string `json:"cover"`
+				Intro      string `json:"intro"`
+				MediaCount int    `json:"media_count"`
+				Upper      struct {
+					Mid  int64  `json:"mid"`
+					Name string `json:"name"`
+					Face string `json:"face"`
+				} `json:"upper"`
+			} `json:"info"`
+			Medias []struct {
+				ID       int64  `json:"id"`
+				Bvid     string `json:"bvid"`
+				Title    string `json:"title"`
+				Cover    string `json:"cover"`
+				Duration int    `json:"duration"`
+				Pubtime  int64  `json:"pubtime"`
+				CntInfo  struct {
+					Play    int64 `json:"play"`
+					Danmaku int64 `json:"danmaku"`
+				} `json:"cnt_info"`
+			} `json:"medias"`
+		}
+		reqURL := fmt.Sprintf("%s?season_id=%d&pn=%d&ps=%d", biliFavSeasonAPI, seasonID, pn, seasonPageSize)
+		if err := getJSON(reqURL, &data); err != nil {
+			return nil, err
+		}
+
+		if pn == 1 {
+			season.Title = data.Info.Title
+			season.Cover = data.Info.Cover
+			season.Intro = data.Info.Intro
+			season.MediaCount = data.Info.MediaCount
+			season.UpperMid = data.Info.Upper.Mid
+			season.UpperName = data.Info.Upper.Name
+			season.UpperFace = data.Info.Upper.Face
+		}
+		for _, m := range data.Medias {
+			season.Episodes = append(season.Episodes, UgcSeasonEpisode{
+				Aid:      m.ID,
+				Bvid:     m.Bvid,
+				Title:    m.Title,
+				Cover:    m.Cover,
+				Duration: m.Duration,
+				Pubtime:  m.Pubtime,
+				Play:     m.CntInfo.Play,
+				Danmaku:  m.CntInfo.Danmaku,
+			})
+		}
+
+		if pn == maxPages || len(data.Medias) < seasonPageSize || len(season.Episodes) >= season.MediaCount {
+			break
+		}
+		time.Sleep(fetchDelay)
+	}
+
+	return season, nil
+}
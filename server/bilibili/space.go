@@ -91,21 +91,22 @@ func GetCachedVideos(mid int64) []SpaceVideo {
 
 // StartBackgroundRefresh launches a goroutine that periodically fetches videos
 // for all followed UPs. Requests are staggered (1/sec) to avoid rate limiting.
-// getFollowedMids should return all unique UP mids across all users.
-func StartBackgroundRefresh(getFollowedMids func() []int64) {
+// getFollowedMids should return all unique UP mids across all users;
+// onFetched, if set, is handed every successful fetch so it can be stored.
+func StartBackgroundRefresh(getFollowedMids func() []int64, onFetched func(mid int64, videos []SpaceVideo)) {
 	go func() {
 		// Run immediately on startup
-		refreshAllMids(getFollowedMids)
+		refreshAllMids(getFollowedMids, onFetched)
 
 		ticker := time.NewTicker(refreshInterval)
 		defer ticker.Stop()
 		for range ticker.C {
-			refreshAllMids(getFollowedMids)
+			refreshAllMids(getFollowedMids, onFetched)
 		}
 	}()
 }
 
-func refreshAllMids(getFollowedMids func() []int64) {
+func refreshAllMids(getFollowedMids func() []int64, onFetched func(mid int64, videos []SpaceVideo)) {
 	mids := getFollowedMids()
 	if len(mids) == 0 {
 		return
@@ -123,7 +124,9 @@ func refreshAllMids(getFollowedMids func() []int64) {
 		}
 		spaceCacheMu.RUnlock()
 
-		fetchUserVideos(mid, videosPerUP)
+		if videos := fetchUserVideos(mid, videosPerUP); videos != nil && onFetched != nil {
+			onFetched(mid, videos)
+		}
 		fetched++
 		time.Sleep(fetchDelay)
 	}
@@ -132,8 +135,9 @@ func refreshAllMids(getFollowedMids func() []int64) {
 }
 
 // fetchUserVideos queries Bilibili's App API for a UP's recent videos
-// and updates the cache. Called by the background refresh task.
-func fetchUserVideos(mid int64, ps int) {
+// and updates the cache. Called by the background refresh task; returns nil
+// on failure.
+func fetchUserVideos(mid int64, ps int) []SpaceVideo {
 	params := url.Values{
 		"vmid":       {fmt.Sprintf("%d", mid)},
 		"ps":         {fmt.Sprintf("%d", ps)},
@@ -156,7 +160,7 @@ func fetchUserVideos(mid int64, ps int) {
 	req, err := http.NewRequest("GET", reqURL, nil)
 	if err != nil {
 		log.Printf("[space] fetchUserVideos mid=%d request build error: %v", mid, err)
-		return
+		return nil
 	}
 	req.Header.Set("User-Agent", appUA)
 	req.Header.Set("Referer", "https://www.bilibili.com")
@@ -165,14 +169,14 @@ func fetchUserVideos(mid int64, ps int) {
 	resp, err := client.Do(req)
 	if err != nil {
 		log.Printf("[space] fetchUserVideos mid=%d network error: %v", mid, err)
-		return
+		return nil
 	}
 	defer resp.Body.Close()
 
 	body, err := io.ReadAll(resp.Body)
 	if err != nil {
 		log.Printf("[space] fetchUserVideos mid=%d read body error: %v", mid, err)
-		return
+		return nil
 	}
 
 	var result struct {
@@ -194,12 +198,12 @@ func fetchUserVideos(mid int64, ps int) {
 
 	if err := json.Unmarshal(body, &result); err != nil {
 		log.Printf("[space] fetchUserVideos mid=%d JSON parse error: %v", mid, err)
-		return
+		return nil
 	}
 
 	if result.Code != 0 {
 		log.Printf("[space] fetchUserVideos mid=%d API error: code=%d msg=%s", mid, result.Code, result.Message)
-		return
+		return nil
 	}
 
 	videos := make([]SpaceVideo, 0, len(result.Data.Item))
@@ -221,4 +225,5 @@ func fetchUserVideos(mid int64, ps int) {
 	spaceCacheMu.Lock()
 	spaceCache[mid] = &spaceCacheEntry{videos: videos, ts: time.Now()}
 	spaceCacheMu.Unlock()
+	return videos
 }
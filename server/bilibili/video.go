@@ -14,14 +14,36 @@ type VideoInfo struct {
 	Aid       int64  `json:"aid"`
 	Bvid      string `json:"bvid"`
 	Title     string `json:"title"`
+	Desc      string `json:"desc"`
 	Pic       string `json:"pic"`
 	Duration  int    `json:"duration"`
 	Pubdate   int64  `json:"pubdate"`
 	Cid       int64  `json:"cid"`
 	Videos    int    `json:"videos"`
+	View      int64  `json:"view"`
 	OwnerMid  int64
 	OwnerName string
 	OwnerFace string
+	Pages     []VideoPage
+	Invalid   bool // deleted or hidden upstream; only Aid/Bvid are set
+}
+
+// VideoPage is one part (分P) of a multi-part video.
+type VideoPage struct {
+	Cid      int64  `json:"cid"`
+	Page     int    `json:"page"`
+	Part     string `json:"part"`
+	Duration int    `json:"duration"`
+}
+
+// PageByCid returns the part with the given cid, or nil if it isn't known.
+func (v *VideoInfo) PageByCid(cid int64) *VideoPage {
+	for i := range v.Pages {
+		if v.Pages[i].Cid == cid {
+			return &v.Pages[i]
+		}
+	}
+	return nil
 }
 
 var (
@@ -31,8 +53,33 @@ var (
 
 const biliViewAPI = "https://api.bilibili.com/x/web-interface/view"
 
+// invalidVideoCodes are the view API codes meaning the video is gone:
+// deleted, hidden, under review, or visible to its uploader only.
+var invalidVideoCodes = map[int]bool{
+	-404:  true,
+	62002: true,
+	62004: true,
+	62012: true,
+}
+
+// RecheckVideoInfo drops any cached metadata for aid and queries Bilibili
+// again, for when a stored video's status has to be re-verified.
+func RecheckVideoInfo(aid int64) (*VideoInfo, error) {
+	cacheMu.Lock()
+	for key, info := range cache {
+		if info.Aid == aid {
+			delete(cache, key)
+		}
+	}
+	delete(cache, fmt.Sprintf("av%d", aid))
+	cacheMu.Unlock()
+
+	return FetchVideoInfo(aid, "")
+}
+
 // FetchVideoInfo queries Bilibili's public API for video metadata.
-// Results are cached in memory to avoid repeated requests.
+// Results are cached in memory to avoid repeated requests. A video known to
+// be gone yields an Invalid placeholder; any other failure is an error.
 func FetchVideoInfo(aid int64, bvid string) (*VideoInfo, error) {
 	// Build cache key
 	key := bvid
@@ -82,6 +129,7 @@ func FetchVideoInfo(aid int64, bvid string) (*VideoInfo, error) {
 			Aid      int64  `json:"aid"`
 			Bvid     string `json:"bvid"`
 			Title    string `json:"title"`
+			Desc     string `json:"desc"`
 			Pic      string `json:"pic"`
 			Duration int    `json:"duration"`
 			Pubdate  int64  `json:"pubdate"`
@@ -92,6 +140,10 @@ func FetchVideoInfo(aid int64, bvid string) (*VideoInfo, error) {
 				Name string `json:"name"`
 				Face string `json:"face"`
 			} `json:"owner"`
+			Pages []VideoPage `json:"pages"`
+			Stat  struct {
+				View int64 `json:"view"`
+			} `json:"stat"`
 		} `json:"data"`
 	}
 
@@ -100,11 +152,17 @@ func FetchVideoInfo(aid int64, bvid string) (*VideoInfo, error) {
 	}
 
 	if result.Code != 0 {
-		// Video may be deleted/unavailable — return a placeholder
+		// Rate limiting and risk control say nothing about the video; fail
+		// so callers keep what they have and retry later.
+		if !invalidVideoCodes[result.Code] {
+			return nil, &APIError{Code: result.Code, Message: result.Message}
+		}
+		// Video is deleted/unavailable — return a placeholder
 		info := &VideoInfo{
-			Aid:   aid,
-			Bvid:  bvid,
-			Title: "已失效视频",
+			Aid:     aid,
+			Bvid:    bvid,
+			Title:   "已失效视频",
+			Invalid: true,
 		}
 		cacheMu.Lock()
 		cache[key] = info
@@ -116,14 +174,17 @@ func FetchVideoInfo(aid int64, bvid string) (*VideoInfo, error) {
 		Aid:       result.Data.Aid,
 		Bvid:      result.Data.Bvid,
 		Title:     result.Data.Title,
+		Desc:      result.Data.Desc,
 		Pic:       result.Data.Pic,
 		Duration:  result.Data.Duration,
 		Pubdate:   result.Data.Pubdate,
 		Cid:       result.Data.Cid,
 		Videos:    result.Data.Videos,
+		View:      result.Data.Stat.View,
 		OwnerMid:  result.Data.Owner.Mid,
 		OwnerName: result.Data.Owner.Name,
 		OwnerFace: result.Data.Owner.Face,
+		Pages:     result.Data.Pages,
 	}
 
 	cacheMu.Lock()
@@ -11,6 +11,7 @@ type Config struct {
 	Database DatabaseConfig `json:"database"`
 	JWT      JWTConfig      `json:"jwt"`
 	Sauc     SaucConfig     `json:"sauc"`
+	Image    ImageConfig    `json:"image"`
 }
 
 type ServerConfig struct {
@@ -40,6 +41,15 @@ type SaucConfig struct {
 	TranscribeConcurrency  int    `json:"transcribe_concurrency"`
 }
 
+// ImageConfig controls the local cover/avatar cache.
+type ImageConfig struct {
+	Dir string `json:"dir"` // where cached images are stored
+	// RewriteURLs makes API responses point image URLs at the proxy
+	// (PublicURL + "/img?sig=...&url=...") instead of Bilibili's CDN.
+	RewriteURLs bool   `json:"rewrite_urls"`
+	PublicURL   string `json:"public_url"` // e.g. "https://pili.example.com"
+}
+
 // DSN returns the MySQL data source name.
 func (d *DatabaseConfig) DSN() string {
 	return d.User + ":" + d.Password + "@tcp(" + d.Host + ":" + d.Port + ")/" + d.DBName + "?charset=utf8mb4&parseTime=True&loc=Local"
@@ -56,6 +66,7 @@ func Get() *Config {
 			Server:   ServerConfig{Port: "8080"},
 			Database: DatabaseConfig{Host: "127.0.0.1", Port: "3306", User: "root", Password: "", DBName: "piliminusb"},
 			JWT:      JWTConfig{Secret: "change-me-to-a-random-secret"},
+			Image:    ImageConfig{Dir: "data/images"},
 			Sauc: SaucConfig{
 				WSURL:                 "wss://openspeech.bytedance.com/api/v3/sauc/bigmodel_nostream",
 				RealtimeWSURL:         "wss://openspeech.bytedance.com/api/v3/sauc/bigmodel",
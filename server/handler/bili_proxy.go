@@ -0,0 +1,39 @@
+package handler
+
+import (
+	"errors"
+
+	"github.com/gin-gonic/gin"
+
+	"piliminusb/bilibili"
+	"piliminusb/response"
+)
+
+// ---------------------------------------------------------------------------
+// GET /proxy/:host/*path  — 匿名只读接口代理 (api / app)
+// ---------------------------------------------------------------------------
+
+// BiliProxy forwards a whitelisted anonymous read, e.g.
+// /proxy/api/x/web-interface/view?bvid=..., so the client never contacts
+// Bilibili for metadata itself.
+func BiliProxy(c *gin.Context) {
+	resp, err := bilibili.ProxyGet(c.Param("host"), c.Param("path"), c.Request.URL.Query())
+	if errors.Is(err, bilibili.ErrProxyNotAllowed) {
+		response.Error(c, 403, -403, "endpoint is not proxied")
+		return
+	} else if err != nil {
+		response.Error(c, 502, -502, err.Error())
+		return
+	}
+
+	if resp.Cached {
+		c.Header("X-Cache", "HIT")
+	} else {
+		c.Header("X-Cache", "MISS")
+	}
+	contentType := resp.ContentType
+	if contentType == "" {
+		contentType = "application/json; charset=utf-8"
+	}
+	c.Data(resp.Status, contentType, resp.Body)
+}
@@ -28,7 +28,10 @@ func DynamicPortal(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 
 	var follows []model.Following
-	database.DB.Where("user_id = ?", userID).Order("m_time DESC").Find(&follows)
+	database.DB.Where("user_id = ? AND attribute IN ?", userID, model.RelationFollowed).
+		Order("m_time DESC").Find(&follows)
+	// The UPs the user watches most come first
+	sortByInteraction(follows, interactionScores(userID), false)
 
 	items := make([]gin.H, 0, len(follows))
 	for _, f := range follows {
@@ -66,10 +69,11 @@ func DynamicFeed(c *gin.Context) {
 	if hostMidStr != "" {
 		mid, _ := strconv.ParseInt(hostMidStr, 10, 64)
 		if mid > 0 {
-			database.DB.Where("user_id = ? AND mid = ?", userID, mid).Find(&follows)
+			database.DB.Where("user_id = ? AND mid = ? AND attribute IN ?", userID, mid, model.RelationFollowed).
+				Find(&follows)
 		}
 	} else {
-		database.DB.Where("user_id = ?", userID).Find(&follows)
+		database.DB.Where("user_id = ? AND attribute IN ?", userID, model.RelationFollowed).Find(&follows)
 	}
 
 	if len(follows) == 0 {
@@ -88,24 +92,7 @@ func DynamicFeed(c *gin.Context) {
 		midMap[follows[i].Mid] = &follows[i]
 	}
 
-	// Read videos from cache (populated by background refresh task)
-	type videoWithOwner struct {
-		bilibili.SpaceVideo
-		OwnerMid int64
-	}
-	var flat []videoWithOwner
-	for _, f := range follows {
-		if vids := bilibili.GetCachedVideos(f.Mid); len(vids) > 0 {
-			for _, v := range vids {
-				flat = append(flat, videoWithOwner{SpaceVideo: v, OwnerMid: f.Mid})
-			}
-		}
-	}
-
-	// Sort by pubdate DESC
-	sort.Slice(flat, func(i, j int) bool {
-		return flat[i].Pubdate > flat[j].Pubdate
-	})
+	flat := followedVideos(follows)
 
 	// Cursor-based pagination: skip items with pubdate >= offset
 	// offset="" means first page; a numeric offset skips older items.
@@ -208,6 +195,27 @@ func DynamicFeed(c *gin.Context) {
 // Helper functions
 // ---------------------------------------------------------------------------
 
+// videoWithOwner is a crawled video tagged with the followed UP it came from.
+type videoWithOwner struct {
+	bilibili.SpaceVideo
+	OwnerMid int64
+}
+
+// followedVideos merges the cached videos of the given follows (populated by
+// the background refresh task), newest first.
+func followedVideos(follows []model.Following) []videoWithOwner {
+	var flat []videoWithOwner
+	for _, f := range follows {
+		for _, v := range bilibili.GetCachedVideos(f.Mid) {
+			flat = append(flat, videoWithOwner{SpaceVideo: v, OwnerMid: f.Mid})
+		}
+	}
+	sort.Slice(flat, func(i, j int) bool {
+		return flat[i].Pubdate > flat[j].Pubdate
+	})
+	return flat
+}
+
 func formatDuration(sec int) string {
 	if sec <= 0 {
 		return "00:00"
@@ -0,0 +1,77 @@
+package handler
+
+import (
+	"fmt"
+
+	"piliminusb/bilibili"
+	"piliminusb/model"
+)
+
+// fillFavResource copies the upstream metadata of a favorited resource into
+// fr, dispatching on its type. Unknown types keep just their id.
+func fillFavResource(fr *model.FavResource) {
+	switch fr.ResourceType {
+	case model.FavTypeAudio:
+		info, err := bilibili.FetchAudioInfo(fr.ResourceID)
+		if err != nil {
+			return
+		}
+		fr.Title = info.Title
+		fr.Cover = info.Cover
+		fr.Intro = info.Intro
+		fr.Duration = info.Duration
+		fr.UpperMid = info.AuthorMid
+		fr.UpperName = info.AuthorName
+		fr.Pubtime = info.Pubtime
+		fr.Play = info.Play
+
+	case model.FavTypeUgcSeason:
+		info, err := bilibili.FetchUgcSeasonInfo(fr.ResourceID)
+		if err != nil {
+			return
+		}
+		fr.Title = info.Title
+		fr.Cover = info.Cover
+		fr.Intro = info.Intro
+		fr.UpperMid = info.UpperMid
+		fr.UpperName = info.UpperName
+
+	case model.FavTypeOgv:
+		info, err := bilibili.FetchPgcEpisode(fr.ResourceID)
+		if err != nil {
+			return
+		}
+		fr.Title = info.SeasonTitle
+		if info.Title != "" {
+			fr.Title = fmt.Sprintf("%s 第%s话", info.SeasonTitle, info.Title)
+		}
+		if info.LongTitle != "" {
+			fr.Title += " " + info.LongTitle
+		}
+		fr.Cover = info.Cover
+		fr.Intro = info.Evaluate
+		fr.Duration = info.Duration
+		fr.Bvid = info.Bvid
+		fr.Cid = info.Cid
+		fr.Pubtime = info.Pubtime
+		fr.Play = info.Views
+		fr.SeasonID = info.SeasonID
+		fr.TypeName = info.TypeName
+
+	default:
+		info, err := bilibili.FetchVideoInfo(fr.ResourceID, "")
+		if err != nil {
+			return
+		}
+		fr.Title = info.Title
+		fr.Cover = info.Pic
+		fr.Duration = info.Duration
+		fr.UpperMid = info.OwnerMid
+		fr.UpperName = info.OwnerName
+		fr.Bvid = info.Bvid
+		fr.Pubtime = info.Pubdate
+		fr.Cid = info.Cid
+		fr.Play = info.View
+		fr.Invalid = info.Invalid
+	}
+}
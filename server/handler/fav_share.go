@@ -0,0 +1,283 @@
+package handler
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Favorite folder privacy & sharing
+// ===========================================================================
+
+const (
+	shareRoleViewer = "viewer"
+	shareRoleEditor = "editor"
+)
+
+// favFolderOwner resolves whose folders a request addresses. Requests for
+// another user's folder carry that user's mid as up_mid and need a share
+// grant on every folder involved: any role to read, editor to write. Public
+// folders can be read by anyone.
+func favFolderOwner(c *gin.Context, mediaIDs []int64, write bool) (uint, bool) {
+	userID := middleware.GetUserID(c)
+
+	upMid := c.Query("up_mid")
+	if upMid == "" {
+		upMid = c.PostForm("up_mid")
+	}
+	ownerMid, _ := strconv.ParseInt(upMid, 10, 64)
+	if ownerMid == 0 || ownerMid == ownerMidFromUser(userID) {
+		return userID, true
+	}
+	ownerID := uint(ownerMid)
+
+	roles := []string{shareRoleViewer, shareRoleEditor}
+	if write {
+		roles = []string{shareRoleEditor}
+	} else {
+		var private int64
+		database.DB.Model(&model.FavFolder{}).
+			Where("user_id = ? AND media_id IN ? AND privacy <> 0", ownerID, mediaIDs).Count(&private)
+		if private == 0 && len(mediaIDs) > 0 {
+			return ownerID, true
+		}
+	}
+	var granted int64
+	database.DB.Model(&model.FavFolderShare{}).
+		Where("owner_id = ? AND user_id = ? AND media_id IN ? AND role IN ?", ownerID, userID, mediaIDs, roles).
+		Count(&granted)
+	return ownerID, len(mediaIDs) > 0 && granted == int64(len(mediaIDs))
+}
+
+// newShareToken returns a random token for share and feed links. It fails
+// rather than hand out a guessable token when the system RNG does.
+func newShareToken() (string, error) {
+	b := make([]byte, 16)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+// requestBaseURL is the scheme and host the client reached us on, for
+// building absolute links.
+func requestBaseURL(c *gin.Context) string {
+	scheme := "http"
+	if c.Request.TLS != nil {
+		scheme = "https"
+	}
+	if proto := c.GetHeader("X-Forwarded-Proto"); proto != "" {
+		scheme = proto
+	}
+	return scheme + "://" + c.Request.Host
+}
+
+// favOwnerInfo fills in the owner's name on folder JSON, which ToBiliJSON
+// leaves empty.
+func favOwnerInfo(m map[string]interface{}, ownerID uint) map[string]interface{} {
+	var owner model.User
+	if database.DB.First(&owner, ownerID).Error == nil {
+		m["upper"] = map[string]interface{}{
+			"mid":  ownerMidFromUser(ownerID),
+			"name": owner.Username,
+			"face": "",
+		}
+	}
+	return m
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v3/fav/folder/share/link  — 公开收藏夹的只读分享链接
+// ---------------------------------------------------------------------------
+
+func FavFolderShareLink(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	mediaID, _ := strconv.ParseInt(c.Query("media_id"), 10, 64)
+	reset := c.Query("reset") == "1" || c.Query("reset") == "true"
+
+	var folder model.FavFolder
+	if err := database.DB.Where("user_id = ? AND media_id = ?", userID, mediaID).First(&folder).Error; err != nil {
+		response.Error(c, 404, -404, "folder not found")
+		return
+	}
+	if folder.Privacy != 0 {
+		response.BadRequest(c, "only public folders can be shared by link")
+		return
+	}
+
+	if folder.ShareToken == "" || reset {
+		token, err := newShareToken()
+		if err != nil {
+			response.InternalError(c, "failed to create share token")
+			return
+		}
+		folder.ShareToken = token
+		database.DB.Model(&folder).Update("share_token", folder.ShareToken)
+	}
+
+	path := "/share/fav/" + folder.ShareToken
+
+	response.Success(c, gin.H{
+		"token": folder.ShareToken,
+		"path":  path,
+		"link":  requestBaseURL(c) + path,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /share/fav/:token  — 通过分享链接查看收藏夹（无需登录）
+// ---------------------------------------------------------------------------
+
+func SharedFavFolder(c *gin.Context) {
+	token := c.Param("token")
+
+	var folder model.FavFolder
+	if token == "" || database.DB.Where("share_token = ? AND privacy = 0", token).First(&folder).Error != nil {
+		response.Error(c, 404, -404, "folder not found")
+		return
+	}
+
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 50 {
+		ps = 20
+	}
+	offset := (pn - 1) * ps
+
+	query := database.DB.Where("user_id = ? AND media_id = ?", folder.UserID, folder.MediaID)
+
+	var total int64
+	query.Model(&model.FavResource{}).Count(&total)
+
+	var items []model.FavResource
+	query.Order(orderClause(favSortKeys(c.DefaultQuery("order", "mtime")), false)).
+		Offset(offset).Limit(ps).Find(&items)
+
+	medias := make([]map[string]interface{}, 0, len(items))
+	for _, item := range items {
+		medias = append(medias, item.ToBiliJSON())
+	}
+
+	response.Success(c, gin.H{
+		"info":     favOwnerInfo(folder.ToBiliJSON(ownerMidFromUser(folder.UserID)), folder.UserID),
+		"medias":   medias,
+		"has_more": int64(offset+ps) < total,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v3/fav/folder/share/list  — 收藏夹共享成员
+// ---------------------------------------------------------------------------
+
+func FavFolderShareList(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	mediaID, _ := strconv.ParseInt(c.Query("media_id"), 10, 64)
+
+	var shares []model.FavFolderShare
+	database.DB.Where("owner_id = ? AND media_id = ?", userID, mediaID).Order("id ASC").Find(&shares)
+
+	ids := make([]uint, 0, len(shares))
+	for _, s := range shares {
+		ids = append(ids, s.UserID)
+	}
+	names := map[uint]string{}
+	if len(ids) > 0 {
+		var users []model.User
+		database.DB.Where("id IN ?", ids).Find(&users)
+		for _, u := range users {
+			names[u.ID] = u.Username
+		}
+	}
+
+	list := make([]gin.H, 0, len(shares))
+	for _, s := range shares {
+		list = append(list, gin.H{
+			"mid":      ownerMidFromUser(s.UserID),
+			"username": names[s.UserID],
+			"role":     s.Role,
+		})
+	}
+	response.Success(c, gin.H{"list": list})
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v3/fav/folder/share/add  — 共享收藏夹给其他用户
+// ---------------------------------------------------------------------------
+
+func FavFolderShareAdd(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	mediaID, _ := strconv.ParseInt(c.PostForm("media_id"), 10, 64)
+	username := c.PostForm("username")
+	role := c.DefaultPostForm("role", shareRoleViewer)
+	if role != shareRoleViewer && role != shareRoleEditor {
+		response.BadRequest(c, "role must be viewer or editor")
+		return
+	}
+
+	var folder model.FavFolder
+	if err := database.DB.Where("user_id = ? AND media_id = ?", userID, mediaID).First(&folder).Error; err != nil {
+		response.Error(c, 404, -404, "folder not found")
+		return
+	}
+
+	var target model.User
+	if username == "" || database.DB.Where("username = ?", username).First(&target).Error != nil {
+		response.BadRequest(c, "user not found")
+		return
+	}
+	if target.ID == userID {
+		response.BadRequest(c, "cannot share a folder with yourself")
+		return
+	}
+
+	var share model.FavFolderShare
+	err := database.DB.Where("owner_id = ? AND media_id = ? AND user_id = ?", userID, mediaID, target.ID).
+		First(&share).Error
+	if err == gorm.ErrRecordNotFound {
+		database.DB.Create(&model.FavFolderShare{
+			OwnerID: userID,
+			MediaID: mediaID,
+			UserID:  target.ID,
+			Role:    role,
+		})
+	} else {
+		database.DB.Model(&share).Update("role", role)
+	}
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v3/fav/folder/share/del  — 取消共享
+// ---------------------------------------------------------------------------
+
+func FavFolderShareDel(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	mediaID, _ := strconv.ParseInt(c.PostForm("media_id"), 10, 64)
+	username := c.PostForm("username")
+
+	var target model.User
+	if username == "" || database.DB.Where("username = ?", username).First(&target).Error != nil {
+		response.BadRequest(c, "user not found")
+		return
+	}
+
+	database.DB.Where("owner_id = ? AND media_id = ? AND user_id = ?", userID, mediaID, target.ID).
+		Delete(&model.FavFolderShare{})
+	response.Success(c, nil)
+}
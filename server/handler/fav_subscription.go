@@ -0,0 +1,484 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Subscribed folders & UGC seasons (收藏的合集/订阅)
+// ===========================================================================
+
+const (
+	seasonRefreshInterval = 6 * time.Hour
+	seasonFetchDelay      = 2 * time.Second
+)
+
+// StartSeasonRefresher launches a goroutine that periodically re-fetches the
+// episode list of every subscribed UGC season.
+func StartSeasonRefresher() {
+	go func() {
+		refreshAllSeasons()
+
+		ticker := time.NewTicker(seasonRefreshInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			refreshAllSeasons()
+		}
+	}()
+}
+
+func refreshAllSeasons() {
+	var ids []int64
+	database.DB.Model(&model.FavSubscription{}).Where("type = ?", model.FavSubUgcSeason).
+		Distinct("target_id").Pluck("target_id", &ids)
+
+	refreshed := 0
+	for _, id := range ids {
+		season, err := bilibili.FetchUgcSeason(id)
+		if err != nil {
+			log.Printf("[season] refresh %d failed: %v", id, err)
+		} else {
+			saveUgcSeason(season)
+			refreshed++
+		}
+		time.Sleep(seasonFetchDelay)
+	}
+	if len(ids) > 0 {
+		log.Printf("[season] background refresh done: %d/%d seasons", refreshed, len(ids))
+	}
+}
+
+// saveUgcSeason stores a season's episodes in order, drops episodes removed
+// from it, and updates every subscriber's snapshot.
+func saveUgcSeason(season *bilibili.UgcSeason) {
+	aids := make([]int64, 0, len(season.Episodes))
+	for i, ep := range season.Episodes {
+		aids = append(aids, ep.Aid)
+		row := model.UgcSeasonEpisode{
+			SeasonID:  season.ID,
+			Aid:       ep.Aid,
+			Position:  i,
+			Bvid:      ep.Bvid,
+			Title:     ep.Title,
+			Cover:     ep.Cover,
+			Duration:  ep.Duration,
+			Pubtime:   ep.Pubtime,
+			Play:      ep.Play,
+			Danmaku:   ep.Danmaku,
+			UpperMid:  season.UpperMid,
+			UpperName: season.UpperName,
+		}
+		var existing model.UgcSeasonEpisode
+		if database.DB.Where("season_id = ? AND aid = ?", season.ID, ep.Aid).
+			First(&existing).Error == gorm.ErrRecordNotFound {
+			database.DB.Create(&row)
+		} else {
+			database.DB.Model(&existing).Updates(map[string]interface{}{
+				"position":   row.Position,
+				"bvid":       row.Bvid,
+				"title":      row.Title,
+				"cover":      row.Cover,
+				"duration":   row.Duration,
+				"pubtime":    row.Pubtime,
+				"play":       row.Play,
+				"danmaku":    row.Danmaku,
+				"upper_mid":  row.UpperMid,
+				"upper_name": row.UpperName,
+			})
+		}
+	}
+	if len(aids) > 0 {
+		database.DB.Where("season_id = ? AND aid NOT IN ?", season.ID, aids).Delete(&model.UgcSeasonEpisode{})
+	}
+
+	database.DB.Model(&model.FavSubscription{}).
+		Where("type = ? AND target_id = ?", model.FavSubUgcSeason, season.ID).
+		Updates(map[string]interface{}{
+			"title":       season.Title,
+			"cover":       season.Cover,
+			"intro":       season.Intro,
+			"upper_mid":   season.UpperMid,
+			"upper_name":  season.UpperName,
+			"upper_face":  season.UpperFace,
+			"media_count": len(season.Episodes),
+		})
+}
+
+// subscriptionJSON converts a subscription to an official collected-folder
+// list item. Folder subscriptions show the live folder, which the caller
+// loads; a folder that was deleted or made private (nil) is reported as
+// invalid (state 1).
+func subscriptionJSON(s *model.FavSubscription, folder *model.FavFolder) map[string]interface{} {
+	item := map[string]interface{}{
+		"id":          s.TargetID,
+		"fid":         s.TargetID,
+		"mid":         s.UpperMid,
+		"attr":        2, // never the default folder
+		"title":       s.Title,
+		"cover":       model.ImageURL(s.Cover),
+		"cover_type":  2,
+		"intro":       s.Intro,
+		"ctime":       s.SubTime,
+		"mtime":       s.SubTime,
+		"state":       0,
+		"fav_state":   1,
+		"media_count": s.MediaCount,
+		"view_count":  0,
+		"is_top":      false,
+		"type":        s.Type,
+		"link":        "",
+		"upper": map[string]interface{}{
+			"mid":  s.UpperMid,
+			"name": s.UpperName,
+			"face": model.ImageURL(s.UpperFace),
+		},
+	}
+
+	if s.Type == model.FavSubFolder {
+		if folder == nil {
+			item["state"] = 1
+			return item
+		}
+		item["attr"] = folder.Attr()
+		item["title"] = folder.Title
+		item["cover"] = model.ImageURL(folder.Cover)
+		item["intro"] = folder.Intro
+		item["media_count"] = folder.MediaCount
+		item["mtime"] = folder.Mtime
+	}
+	return item
+}
+
+// folderKey addresses a favorite folder across users.
+type folderKey struct {
+	owner   uint
+	mediaID int64
+}
+
+// publicFolders loads the public folders behind the given folder
+// subscriptions in one query.
+func publicFolders(subs []model.FavSubscription) map[folderKey]*model.FavFolder {
+	var keys [][]interface{}
+	for _, s := range subs {
+		if s.Type == model.FavSubFolder {
+			keys = append(keys, []interface{}{s.OwnerID, s.TargetID})
+		}
+	}
+	out := map[folderKey]*model.FavFolder{}
+	if len(keys) == 0 {
+		return out
+	}
+	var folders []model.FavFolder
+	database.DB.Where("(user_id, media_id) IN ? AND privacy = 0", keys).Find(&folders)
+	for i := range folders {
+		out[folderKey{folders[i].UserID, folders[i].MediaID}] = &folders[i]
+	}
+	return out
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v3/fav/folder/collected/list  — 收藏的收藏夹/合集
+// ---------------------------------------------------------------------------
+
+// collectedRow is one entry of the merged share and subscription list.
+type collectedRow struct {
+	Kind string
+	ID   uint
+	At   int64
+}
+
+// CollectedFavFolders lists folders shared with the user together with the
+// folders and UGC seasons they subscribed to, most recent first.
+func CollectedFavFolders(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 100 {
+		ps = 20
+	}
+	offset := (pn - 1) * ps
+
+	// Shares of folders that still exist, merged with subscriptions by time
+	shared := database.DB.Table("fav_folder_shares AS s").
+		Joins("JOIN fav_folders AS f ON f.user_id = s.owner_id AND f.media_id = s.media_id").
+		Where("s.user_id = ?", userID).Session(&gorm.Session{})
+
+	var shareCount, subCount int64
+	shared.Count(&shareCount)
+	database.DB.Model(&model.FavSubscription{}).Where("user_id = ?", userID).Count(&subCount)
+	total := shareCount + subCount
+
+	var rows []collectedRow
+	database.DB.Raw(`SELECT 'share' AS kind, s.id AS id, UNIX_TIMESTAMP(s.created_at) AS at
+		FROM fav_folder_shares AS s
+		JOIN fav_folders AS f ON f.user_id = s.owner_id AND f.media_id = s.media_id
+		WHERE s.user_id = ?
+		UNION ALL
+		SELECT 'sub' AS kind, id, sub_time AS at FROM fav_subscriptions WHERE user_id = ?
+		ORDER BY at DESC, kind ASC, id DESC
+		LIMIT ? OFFSET ?`, userID, userID, ps, offset).Scan(&rows)
+
+	var shareIDs, subIDs []uint
+	for _, r := range rows {
+		if r.Kind == "share" {
+			shareIDs = append(shareIDs, r.ID)
+		} else {
+			subIDs = append(subIDs, r.ID)
+		}
+	}
+
+	// Shared folders with their owners, one query each
+	type sharedFolder struct {
+		model.FavFolder
+		ShareID uint
+		Role    string
+	}
+	shares := map[uint]*sharedFolder{}
+	owners := map[uint]string{}
+	if len(shareIDs) > 0 {
+		var found []sharedFolder
+		shared.Select("f.*, s.id AS share_id, s.role AS role").Where("s.id IN ?", shareIDs).Scan(&found)
+		var ownerIDs []uint
+		for i := range found {
+			shares[found[i].ShareID] = &found[i]
+			ownerIDs = append(ownerIDs, found[i].UserID)
+		}
+		var users []model.User
+		database.DB.Where("id IN ?", ownerIDs).Find(&users)
+		for _, u := range users {
+			owners[u.ID] = u.Username
+		}
+	}
+
+	subs := map[uint]*model.FavSubscription{}
+	var folders map[folderKey]*model.FavFolder
+	if len(subIDs) > 0 {
+		var found []model.FavSubscription
+		database.DB.Where("id IN ?", subIDs).Find(&found)
+		for i := range found {
+			subs[found[i].ID] = &found[i]
+		}
+		folders = publicFolders(found)
+	}
+
+	list := make([]map[string]interface{}, 0, len(rows))
+	for _, r := range rows {
+		if r.Kind == "share" {
+			sf := shares[r.ID]
+			if sf == nil {
+				continue
+			}
+			item := sf.FavFolder.ToBiliJSON(ownerMidFromUser(sf.UserID))
+			item["upper"] = map[string]interface{}{
+				"mid":  ownerMidFromUser(sf.UserID),
+				"name": owners[sf.UserID],
+				"face": "",
+			}
+			item["type"] = model.FavSubFolder
+			item["role"] = sf.Role
+			list = append(list, item)
+		} else if sub := subs[r.ID]; sub != nil {
+			list = append(list, subscriptionJSON(sub, folders[folderKey{sub.OwnerID, sub.TargetID}]))
+		}
+	}
+
+	response.Success(c, gin.H{
+		"count":    total,
+		"list":     list,
+		"has_more": int64(offset+ps) < total,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v3/fav/folder/fav  — 订阅其他用户的公开收藏夹
+// ---------------------------------------------------------------------------
+
+func FavFolderSubscribe(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	mediaID, _ := strconv.ParseInt(c.PostForm("media_id"), 10, 64)
+	ownerMid, _ := strconv.ParseInt(c.PostForm("up_mid"), 10, 64)
+	ownerID := uint(ownerMid)
+	if mediaID == 0 || ownerMid == 0 {
+		response.BadRequest(c, "media_id and up_mid are required")
+		return
+	}
+	if ownerID == userID {
+		response.BadRequest(c, "cannot subscribe to your own folder")
+		return
+	}
+
+	var folder model.FavFolder
+	if database.DB.Where("user_id = ? AND media_id = ? AND privacy = 0", ownerID, mediaID).
+		First(&folder).Error != nil {
+		response.Error(c, 404, -404, "folder not found")
+		return
+	}
+	var owner model.User
+	database.DB.First(&owner, ownerID)
+
+	sub := model.FavSubscription{
+		UserID:     userID,
+		Type:       model.FavSubFolder,
+		OwnerID:    ownerID,
+		TargetID:   mediaID,
+		Title:      folder.Title,
+		Cover:      folder.Cover,
+		Intro:      folder.Intro,
+		UpperMid:   ownerMid,
+		UpperName:  owner.Username,
+		MediaCount: folder.MediaCount,
+		SubTime:    time.Now().Unix(),
+	}
+	if database.DB.Where("user_id = ? AND type = ? AND owner_id = ? AND target_id = ?",
+		userID, model.FavSubFolder, ownerID, mediaID).First(&model.FavSubscription{}).Error == gorm.ErrRecordNotFound {
+		database.DB.Create(&sub)
+	}
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v3/fav/folder/unfav  — 取消订阅收藏夹
+// ---------------------------------------------------------------------------
+
+func FavFolderUnsubscribe(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	mediaID, _ := strconv.ParseInt(c.PostForm("media_id"), 10, 64)
+	ownerMid, _ := strconv.ParseInt(c.PostForm("up_mid"), 10, 64)
+
+	query := database.DB.Where("user_id = ? AND type = ? AND target_id = ?", userID, model.FavSubFolder, mediaID)
+	if ownerMid != 0 {
+		query = query.Where("owner_id = ?", uint(ownerMid))
+	}
+	query.Delete(&model.FavSubscription{})
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v3/fav/season/fav  — 订阅合集
+// ---------------------------------------------------------------------------
+
+func FavSeasonSubscribe(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	seasonID, _ := strconv.ParseInt(c.PostForm("season_id"), 10, 64)
+	if seasonID == 0 {
+		response.BadRequest(c, "season_id is required")
+		return
+	}
+
+	season, err := bilibili.FetchUgcSeason(seasonID)
+	if err != nil {
+		response.InternalError(c, "failed to fetch season: "+err.Error())
+		return
+	}
+
+	if database.DB.Where("user_id = ? AND type = ? AND target_id = ?", userID, model.FavSubUgcSeason, seasonID).
+		First(&model.FavSubscription{}).Error == gorm.ErrRecordNotFound {
+		database.DB.Create(&model.FavSubscription{
+			UserID:   userID,
+			Type:     model.FavSubUgcSeason,
+			TargetID: seasonID,
+			SubTime:  time.Now().Unix(),
+		})
+	}
+	saveUgcSeason(season)
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v3/fav/season/unfav  — 取消订阅合集
+// ---------------------------------------------------------------------------
+
+func FavSeasonUnsubscribe(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	seasonID, _ := strconv.ParseInt(c.PostForm("season_id"), 10, 64)
+	database.DB.Where("user_id = ? AND type = ? AND target_id = ?", userID, model.FavSubUgcSeason, seasonID).
+		Delete(&model.FavSubscription{})
+
+	// Drop the cached episodes once nobody subscribes any more
+	var remaining int64
+	database.DB.Model(&model.FavSubscription{}).
+		Where("type = ? AND target_id = ?", model.FavSubUgcSeason, seasonID).Count(&remaining)
+	if remaining == 0 {
+		database.DB.Where("season_id = ?", seasonID).Delete(&model.UgcSeasonEpisode{})
+	}
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/space/fav/season/list  — 合集内容
+// ---------------------------------------------------------------------------
+
+func FavSeasonList(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	seasonID, _ := strconv.ParseInt(c.Query("season_id"), 10, 64)
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if seasonID == 0 {
+		response.BadRequest(c, "season_id is required")
+		return
+	}
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 20 {
+		ps = 20
+	}
+	offset := (pn - 1) * ps
+
+	var sub model.FavSubscription
+	if database.DB.Where("user_id = ? AND type = ? AND target_id = ?", userID, model.FavSubUgcSeason, seasonID).
+		First(&sub).Error != nil {
+		response.Error(c, 404, -404, "season not subscribed")
+		return
+	}
+
+	// Subscribed before the first fetch finished: load it now
+	var total int64
+	database.DB.Model(&model.UgcSeasonEpisode{}).Where("season_id = ?", seasonID).Count(&total)
+	if total == 0 {
+		if season, err := bilibili.FetchUgcSeason(seasonID); err == nil {
+			saveUgcSeason(season)
+			database.DB.First(&sub, sub.ID)
+			total = int64(len(season.Episodes))
+		}
+	}
+
+	var episodes []model.UgcSeasonEpisode
+	database.DB.Where("season_id = ?", seasonID).Order("position ASC").
+		Offset(offset).Limit(ps).Find(&episodes)
+
+	medias := make([]map[string]interface{}, 0, len(episodes))
+	for i := range episodes {
+		medias = append(medias, episodes[i].ToBiliJSON())
+	}
+
+	response.Success(c, gin.H{
+		"info":     subscriptionJSON(&sub, nil),
+		"medias":   medias,
+		"has_more": int64(offset+ps) < total,
+	})
+}
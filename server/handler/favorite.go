@@ -9,7 +9,6 @@ import (
 	"github.com/gin-gonic/gin"
 	"gorm.io/gorm"
 
-	"piliminusb/bilibili"
 	"piliminusb/database"
 	"piliminusb/middleware"
 	"piliminusb/model"
@@ -53,9 +52,10 @@ func AllFavFolders(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 	ownerMid := ownerMidFromUser(userID)
 
-	// Optional: rid (resource aid) to mark which folders contain this resource.
+	// Optional: rid (resource id) and type to mark which folders contain this resource.
 	ridStr := c.DefaultQuery("rid", "0")
 	rid, _ := strconv.ParseInt(ridStr, 10, 64)
+	rtype, _ := strconv.Atoi(c.DefaultQuery("type", "2"))
 
 	var folders []model.FavFolder
 	database.DB.Where("user_id = ?", userID).Order("sort_order ASC, media_id ASC").Find(&folders)
@@ -64,7 +64,7 @@ func AllFavFolders(c *gin.Context) {
 	favSet := map[int64]bool{}
 	if rid > 0 {
 		var ress []model.FavResource
-		database.DB.Where("user_id = ? AND resource_id = ?", userID, rid).Find(&ress)
+		database.DB.Where("user_id = ? AND resource_id = ? AND resource_type = ?", userID, rid, rtype).Find(&ress)
 		for _, r := range ress {
 			favSet[r.MediaID] = true
 		}
@@ -122,9 +122,6 @@ func ListFavFolders(c *gin.Context) {
 // ---------------------------------------------------------------------------
 
 func FavFolderInfo(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-	ownerMid := ownerMidFromUser(userID)
-
 	mediaIDStr := c.Query("media_id")
 	mediaID, _ := strconv.ParseInt(mediaIDStr, 10, 64)
 	if mediaID == 0 {
@@ -132,6 +129,13 @@ func FavFolderInfo(c *gin.Context) {
 		return
 	}
 
+	userID, ok := favFolderOwner(c, []int64{mediaID}, false)
+	if !ok {
+		response.Error(c, 403, -403, "no access to this folder")
+		return
+	}
+	ownerMid := ownerMidFromUser(userID)
+
 	var folder model.FavFolder
 	if err := database.DB.Where("user_id = ? AND media_id = ?", userID, mediaID).First(&folder).Error; err != nil {
 		response.Error(c, 404, -404, "folder not found")
@@ -150,10 +154,15 @@ func AddFavFolder(c *gin.Context) {
 
 	title := c.PostForm("title")
 	intro := c.PostForm("intro")
+	privacy, _ := strconv.Atoi(c.DefaultPostForm("privacy", "1"))
 	if title == "" {
 		response.BadRequest(c, "title is required")
 		return
 	}
+	if privacy != 0 && privacy != 1 {
+		response.BadRequest(c, "privacy must be 0 or 1")
+		return
+	}
 
 	now := time.Now().Unix()
 	mediaID := nextMediaID(userID)
@@ -175,8 +184,14 @@ func AddFavFolder(c *gin.Context) {
 		Mtime:     now,
 		SortOrder: int(cnt), // append at end
 		IsDefault: isDefault,
+		Privacy:   privacy,
 	}
 	database.DB.Create(&folder)
+	if privacy == 0 {
+		// Create leaves out zero values, so the column default (private) applied
+		database.DB.Model(&folder).Update("privacy", 0)
+		folder.Privacy = 0
+	}
 
 	ownerMid := ownerMidFromUser(userID)
 	response.Success(c, folder.ToBiliJSON(ownerMid))
@@ -216,6 +231,14 @@ func EditFavFolder(c *gin.Context) {
 	if cover != "" {
 		updates["cover"] = cover
 	}
+	if privacyStr, ok := c.GetPostForm("privacy"); ok {
+		privacy, err := strconv.Atoi(privacyStr)
+		if err != nil || (privacy != 0 && privacy != 1) {
+			response.BadRequest(c, "privacy must be 0 or 1")
+			return
+		}
+		updates["privacy"] = privacy
+	}
 
 	database.DB.Model(&model.FavFolder{}).
 		Where("user_id = ? AND media_id = ?", userID, mediaID).Updates(updates)
@@ -245,6 +268,7 @@ func DelFavFolder(c *gin.Context) {
 	// Delete folder contents and the folders themselves.
 	database.DB.Where("user_id = ? AND media_id IN ?", userID, ids).Delete(&model.FavResource{})
 	database.DB.Where("user_id = ? AND media_id IN ?", userID, ids).Delete(&model.FavFolder{})
+	database.DB.Where("owner_id = ? AND media_id IN ?", userID, ids).Delete(&model.FavFolderShare{})
 
 	response.Success(c, nil)
 }
@@ -284,9 +308,6 @@ func SortFavFolder(c *gin.Context) {
 // ---------------------------------------------------------------------------
 
 func ListFavResources(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-	ownerMid := ownerMidFromUser(userID)
-
 	mediaIDStr := c.Query("media_id")
 	mediaID, _ := strconv.ParseInt(mediaIDStr, 10, 64)
 	if mediaID == 0 {
@@ -294,6 +315,13 @@ func ListFavResources(c *gin.Context) {
 		return
 	}
 
+	userID, ok := favFolderOwner(c, []int64{mediaID}, false)
+	if !ok {
+		response.Error(c, 403, -403, "no access to this folder")
+		return
+	}
+	ownerMid := ownerMidFromUser(userID)
+
 	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
 	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
 	keyword := c.DefaultQuery("keyword", "")
@@ -313,23 +341,11 @@ func ListFavResources(c *gin.Context) {
 		query = query.Where("title LIKE ?", "%"+keyword+"%")
 	}
 
-	// Order
-	orderClause := "fav_time DESC"
-	switch order {
-	case "mtime":
-		orderClause = "fav_time DESC"
-	case "view":
-		orderClause = "fav_time DESC" // no view count, fallback
-	case "pubtime":
-		orderClause = "pubtime DESC"
-	}
+	var total int64
+	query.Model(&model.FavResource{}).Count(&total)
 
 	var items []model.FavResource
-	query.Order(orderClause).Offset(offset).Limit(ps).Find(&items)
-
-	var total int64
-	database.DB.Model(&model.FavResource{}).
-		Where("user_id = ? AND media_id = ?", userID, mediaID).Count(&total)
+	query.Order(orderClause(favSortKeys(order), false)).Offset(offset).Limit(ps).Find(&items)
 
 	medias := make([]map[string]interface{}, 0, len(items))
 	for _, item := range items {
@@ -349,36 +365,22 @@ func ListFavResources(c *gin.Context) {
 // ---------------------------------------------------------------------------
 
 func BatchDealFav(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-
 	resourcesStr := c.PostForm("resources")
 	addIdsStr := c.PostForm("add_media_ids")
 	delIdsStr := c.PostForm("del_media_ids")
 
-	// Parse resources: can be a JSON array or comma-separated "aid:type" pairs.
-	type resItem struct {
-		Rid  int64 `json:"rid"`
-		Type int   `json:"type"`
-	}
-	var resources []resItem
-
-	// Try JSON first
+	// Parse resources: can be a JSON array or comma-separated "id:type" pairs.
+	var resources []favResourceRef
 	if err := json.Unmarshal([]byte(resourcesStr), &resources); err != nil {
-		// Fallback: comma-separated "aid:type"
-		for _, part := range strings.Split(resourcesStr, ",") {
-			part = strings.TrimSpace(part)
-			if part == "" {
-				continue
-			}
-			segs := strings.SplitN(part, ":", 2)
-			rid, _ := strconv.ParseInt(segs[0], 10, 64)
-			rtype := 2
-			if len(segs) > 1 {
-				rtype, _ = strconv.Atoi(segs[1])
-			}
-			if rid > 0 {
-				resources = append(resources, resItem{Rid: rid, Type: rtype})
-			}
+		resources = parseFavResources(resourcesStr)
+	}
+	for i := range resources {
+		if resources[i].Type == 0 {
+			resources[i].Type = model.FavTypeVideo
+		}
+		if !model.FavTypeSupported(resources[i].Type) {
+			response.BadRequest(c, "unsupported resource type")
+			return
 		}
 	}
 
@@ -390,6 +392,12 @@ func BatchDealFav(c *gin.Context) {
 	addIds := parseIntList(addIdsStr)
 	delIds := parseIntList(delIdsStr)
 
+	userID, ok := favFolderOwner(c, append(append([]int64{}, addIds...), delIds...), true)
+	if !ok {
+		response.Error(c, 403, -403, "no edit access to these folders")
+		return
+	}
+
 	now := time.Now().Unix()
 
 	tx := database.DB.Begin()
@@ -397,9 +405,6 @@ func BatchDealFav(c *gin.Context) {
 	// Add resources to target folders
 	for _, mid := range addIds {
 		for _, res := range resources {
-			// Fetch metadata
-			info, _ := bilibili.FetchVideoInfo(res.Rid, "")
-
 			fr := model.FavResource{
 				UserID:       userID,
 				MediaID:      mid,
@@ -407,21 +412,13 @@ func BatchDealFav(c *gin.Context) {
 				ResourceType: res.Type,
 				FavTime:      now,
 			}
-			if info != nil {
-				fr.Title = info.Title
-				fr.Cover = info.Pic
-				fr.Duration = info.Duration
-				fr.UpperMid = info.OwnerMid
-				fr.UpperName = info.OwnerName
-				fr.Bvid = info.Bvid
-				fr.Pubtime = info.Pubdate
-				fr.Cid = info.Cid
-			}
 
 			// Upsert
 			var existing model.FavResource
-			if tx.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, mid, res.Rid).
+			if tx.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+				userID, mid, fr.ResourceID, fr.ResourceType).
 				First(&existing).Error == gorm.ErrRecordNotFound {
+				fillFavResource(&fr)
 				tx.Create(&fr)
 			}
 		}
@@ -429,12 +426,11 @@ func BatchDealFav(c *gin.Context) {
 
 	// Remove resources from target folders
 	for _, mid := range delIds {
-		rids := make([]int64, 0, len(resources))
 		for _, res := range resources {
-			rids = append(rids, res.Rid)
+			tx.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+				userID, mid, res.Rid, res.Type).
+				Delete(&model.FavResource{})
 		}
-		tx.Where("user_id = ? AND media_id = ? AND resource_id IN ?", userID, mid, rids).
-			Delete(&model.FavResource{})
 	}
 
 	if err := tx.Commit().Error; err != nil {
@@ -467,16 +463,19 @@ func UnfavAll(c *gin.Context) {
 		return
 	}
 
+	rtype, _ := strconv.Atoi(c.DefaultPostForm("type", "2"))
+
 	// Find all folders that contain this resource, then delete.
 	var affected []model.FavResource
-	database.DB.Where("user_id = ? AND resource_id = ?", userID, rid).Find(&affected)
+	database.DB.Where("user_id = ? AND resource_id = ? AND resource_type = ?", userID, rid, rtype).Find(&affected)
 
 	affectedMediaIDs := map[int64]bool{}
 	for _, r := range affected {
 		affectedMediaIDs[r.MediaID] = true
 	}
 
-	database.DB.Where("user_id = ? AND resource_id = ?", userID, rid).Delete(&model.FavResource{})
+	database.DB.Where("user_id = ? AND resource_id = ? AND resource_type = ?", userID, rid, rtype).
+		Delete(&model.FavResource{})
 
 	for mid := range affectedMediaIDs {
 		refreshMediaCount(userID, mid)
@@ -504,21 +503,24 @@ func CopyFavResource(c *gin.Context) {
 		return
 	}
 
-	rids := parseIntList(resourcesStr)
+	resources := parseFavResources(resourcesStr)
 	now := time.Now().Unix()
 
-	for _, rid := range rids {
+	for _, res := range resources {
 		var src model.FavResource
-		if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, srcMediaID, rid).
+		if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+			userID, srcMediaID, res.Rid, res.Type).
 			First(&src).Error == nil {
 			// Copy to target
 			var existing model.FavResource
-			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, tarMediaID, rid).
+			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+				userID, tarMediaID, res.Rid, res.Type).
 				First(&existing).Error == gorm.ErrRecordNotFound {
 				newRes := src
 				newRes.ID = 0
 				newRes.MediaID = tarMediaID
 				newRes.FavTime = now
+				newRes.SortOrder = 0
 				database.DB.Create(&newRes)
 			}
 		}
@@ -547,26 +549,28 @@ func MoveFavResource(c *gin.Context) {
 		return
 	}
 
-	rids := parseIntList(resourcesStr)
+	resources := parseFavResources(resourcesStr)
 	now := time.Now().Unix()
 
-	for _, rid := range rids {
+	for _, res := range resources {
 		var src model.FavResource
-		if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, srcMediaID, rid).
+		if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+			userID, srcMediaID, res.Rid, res.Type).
 			First(&src).Error == nil {
 			// Copy to target
 			var existing model.FavResource
-			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, tarMediaID, rid).
+			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+				userID, tarMediaID, res.Rid, res.Type).
 				First(&existing).Error == gorm.ErrRecordNotFound {
 				newRes := src
 				newRes.ID = 0
 				newRes.MediaID = tarMediaID
 				newRes.FavTime = now
+				newRes.SortOrder = 0
 				database.DB.Create(&newRes)
 			}
 			// Remove from source
-			database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, srcMediaID, rid).
-				Delete(&model.FavResource{})
+			database.DB.Delete(&src)
 		}
 	}
 
@@ -580,7 +584,36 @@ func MoveFavResource(c *gin.Context) {
 // ---------------------------------------------------------------------------
 
 func CleanFavResource(c *gin.Context) {
-	// Self-hosted server has no concept of "invalid" resources.
+	mediaID, _ := strconv.ParseInt(c.PostForm("media_id"), 10, 64)
+	if mediaID == 0 {
+		response.BadRequest(c, "media_id is required")
+		return
+	}
+
+	userID, ok := favFolderOwner(c, []int64{mediaID}, true)
+	if !ok {
+		response.Error(c, 403, -403, "no edit access to this folder")
+		return
+	}
+
+	// Remove resources the validator found deleted upstream, keeping a
+	// snapshot of what they were.
+	var invalid []model.FavResource
+	database.DB.Where("user_id = ? AND media_id = ? AND invalid = ?", userID, mediaID, true).Find(&invalid)
+	if len(invalid) > 0 {
+		err := database.DB.Transaction(func(tx *gorm.DB) error {
+			if err := tx.Create(lostFromFav(invalid, time.Now().Unix())).Error; err != nil {
+				return err
+			}
+			return tx.Delete(&invalid).Error
+		})
+		if err != nil {
+			response.InternalError(c, "clean failed: "+err.Error())
+			return
+		}
+		refreshMediaCount(userID, mediaID)
+	}
+
 	response.Success(c, nil)
 }
 
@@ -589,8 +622,6 @@ func CleanFavResource(c *gin.Context) {
 // ---------------------------------------------------------------------------
 
 func SortFavResource(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-
 	mediaIDStr := c.PostForm("media_id")
 	mediaID, _ := strconv.ParseInt(mediaIDStr, 10, 64)
 	resourcesStr := c.PostForm("sort")
@@ -603,16 +634,109 @@ func SortFavResource(c *gin.Context) {
 		return
 	}
 
-	rids := parseIntList(resourcesStr)
-	for i, rid := range rids {
-		database.DB.Model(&model.FavResource{}).
-			Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, mediaID, rid).
-			Update("sort_order", i)
+	userID, ok := favFolderOwner(c, []int64{mediaID}, true)
+	if !ok {
+		response.Error(c, 403, -403, "no edit access to this folder")
+		return
 	}
 
+	order, rowOf := favManualOrder(userID, mediaID)
+
+	// The official format is a list of moves, "prev_id:prev_type:id:type",
+	// each placing id right after prev_id (0:0 = to the top) and applied in
+	// turn. A plain id list (videos, or "id:type") is taken as the new order
+	// of those resources. Either way the order is kept in row ids, since
+	// resources of different types may share a numeric id.
+	var ids []int64
+	for _, part := range strings.Split(resourcesStr, ",") {
+		segs := strings.Split(strings.TrimSpace(part), ":")
+		if len(segs) == 4 {
+			prev, _ := strconv.ParseInt(segs[0], 10, 64)
+			prevType, _ := strconv.Atoi(segs[1])
+			rid, _ := strconv.ParseInt(segs[2], 10, 64)
+			rtype, _ := strconv.Atoi(segs[3])
+			row, ok := rowOf[favResourceRef{rid, rtype}]
+			if !ok {
+				continue
+			}
+			if prev == 0 {
+				order = moveAfter(order, row, 0)
+			} else if prevRow, ok := rowOf[favResourceRef{prev, prevType}]; ok && prevRow != row {
+				order = moveAfter(order, row, prevRow)
+			}
+		} else if refs := parseFavResources(part); len(refs) == 1 {
+			if row, ok := rowOf[refs[0]]; ok {
+				ids = append(ids, row)
+			}
+		}
+	}
+	if len(ids) > 0 {
+		order = reorderSlots(order, ids)
+	}
+
+	database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, row := range order {
+			if err := tx.Model(&model.FavResource{}).
+				Where("id = ? AND user_id = ?", row, userID).
+				Update("sort_order", i+1).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+
 	response.Success(c, nil)
 }
 
+// favSortKeys maps the official order values to the order of a folder's
+// resources. mtime ("最近收藏") follows the manual order once the folder has
+// been sorted; resources added since then come first, newest first. view
+// uses the play count stored when a video was added, which the video
+// validator keeps current (and fills in for favorites stored without one).
+func favSortKeys(order string) []sortKey {
+	switch order {
+	case "view":
+		return []sortKey{{"play", true}, {"id", true}}
+	case "pubtime":
+		return []sortKey{{"pubtime", true}, {"id", true}}
+	default:
+		return []sortKey{{"sort_order", false}, {"fav_time", true}, {"id", true}}
+	}
+}
+
+// favSortValue returns the value of a sort column for a cursor resource.
+func favSortValue(r *model.FavResource, column string) interface{} {
+	switch column {
+	case "id":
+		return r.ID
+	case "play":
+		return r.Play
+	case "pubtime":
+		return r.Pubtime
+	case "sort_order":
+		return r.SortOrder
+	default:
+		return r.FavTime
+	}
+}
+
+// favManualOrder returns a folder's resource row ids in manual order, and
+// the row id of each resource.
+func favManualOrder(userID uint, mediaID int64) ([]int64, map[favResourceRef]int64) {
+	var rows []model.FavResource
+	database.DB.Select("id, resource_id, resource_type").
+		Where("user_id = ? AND media_id = ?", userID, mediaID).
+		Order(orderClause(favSortKeys("mtime"), false)).Find(&rows)
+
+	order := make([]int64, 0, len(rows))
+	rowOf := make(map[favResourceRef]int64, len(rows))
+	for _, r := range rows {
+		order = append(order, int64(r.ID))
+		rowOf[favResourceRef{r.ResourceID, r.ResourceType}] = int64(r.ID)
+	}
+	return order, rowOf
+}
+
 // ===========================================================================
 // Phase 3 – Watch Later ↔ Favorites Cross-Operations
 // ===========================================================================
@@ -643,13 +767,14 @@ func ToviewCopy(c *gin.Context) {
 		var wl model.WatchLater
 		if database.DB.Where("user_id = ? AND aid = ?", userID, aid).First(&wl).Error == nil {
 			var existing model.FavResource
-			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, mediaID, aid).
+			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+				userID, mediaID, aid, model.FavTypeVideo).
 				First(&existing).Error == gorm.ErrRecordNotFound {
 				fr := model.FavResource{
 					UserID:       userID,
 					MediaID:      mediaID,
 					ResourceID:   wl.Aid,
-					ResourceType: 2,
+					ResourceType: model.FavTypeVideo,
 					Title:        wl.Title,
 					Cover:        wl.Pic,
 					Duration:     wl.Duration,
@@ -695,13 +820,14 @@ func ToviewMove(c *gin.Context) {
 		var wl model.WatchLater
 		if database.DB.Where("user_id = ? AND aid = ?", userID, aid).First(&wl).Error == nil {
 			var existing model.FavResource
-			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, mediaID, aid).
+			if database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ? AND resource_type = ?",
+				userID, mediaID, aid, model.FavTypeVideo).
 				First(&existing).Error == gorm.ErrRecordNotFound {
 				fr := model.FavResource{
 					UserID:       userID,
 					MediaID:      mediaID,
 					ResourceID:   wl.Aid,
-					ResourceType: 2,
+					ResourceType: model.FavTypeVideo,
 					Title:        wl.Title,
 					Cover:        wl.Pic,
 					Duration:     wl.Duration,
@@ -727,6 +853,36 @@ func ToviewMove(c *gin.Context) {
 // Helpers
 // ===========================================================================
 
+// favResourceRef identifies a favorited resource by id and type.
+type favResourceRef struct {
+	Rid  int64 `json:"rid"`
+	Type int   `json:"type"`
+}
+
+// parseFavResources parses comma-separated "id:type" pairs; a bare id is a
+// video.
+func parseFavResources(s string) []favResourceRef {
+	var refs []favResourceRef
+	for _, part := range strings.Split(s, ",") {
+		part = strings.TrimSpace(part)
+		if part == "" {
+			continue
+		}
+		segs := strings.SplitN(part, ":", 2)
+		rid, _ := strconv.ParseInt(segs[0], 10, 64)
+		rtype := model.FavTypeVideo
+		if len(segs) > 1 {
+			if t, err := strconv.Atoi(segs[1]); err == nil && t > 0 {
+				rtype = t
+			}
+		}
+		if rid > 0 {
+			refs = append(refs, favResourceRef{Rid: rid, Type: rtype})
+		}
+	}
+	return refs
+}
+
 // parseIntList splits a comma-separated string into int64 values.
 func parseIntList(s string) []int64 {
 	parts := strings.Split(s, ",")
@@ -0,0 +1,449 @@
+package handler
+
+import (
+	"encoding/json"
+	"encoding/xml"
+	"fmt"
+	"html"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Feed reader output (RSS 2.0 / Atom / JSON Feed) of the followed-UP feed
+// ===========================================================================
+
+const (
+	feedDefaultLimit = 30
+	feedMaxLimit     = 100
+	// Only the newest entries get a description, which needs the video info;
+	// lookups run a few at a time.
+	feedDescLimit       = 20
+	feedDescConcurrency = 4
+)
+
+// feedEntry is one video of a feed, independent of the output format.
+type feedEntry struct {
+	Aid        int64
+	Bvid       string
+	Title      string
+	Desc       string
+	Cover      string
+	Duration   int
+	Pubdate    int64
+	AuthorMid  int64
+	AuthorName string
+}
+
+func (e *feedEntry) link() string {
+	if e.Bvid != "" {
+		return "https://www.bilibili.com/video/" + e.Bvid
+	}
+	return fmt.Sprintf("https://www.bilibili.com/video/av%d", e.Aid)
+}
+
+func (e *feedEntry) authorLink() string {
+	return fmt.Sprintf("https://space.bilibili.com/%d", e.AuthorMid)
+}
+
+// contentHTML renders cover, duration, uploader and description.
+func (e *feedEntry) contentHTML() string {
+	var b strings.Builder
+	if e.Cover != "" {
+		fmt.Fprintf(&b, `<p><a href="%s"><img src="%s" alt="%s"/></a></p>`,
+			html.EscapeString(e.link()), html.EscapeString(model.ImageURL(e.Cover)), html.EscapeString(e.Title))
+	}
+	fmt.Fprintf(&b, "<p>时长 %s · UP主 %s</p>", formatDuration(e.Duration), html.EscapeString(e.AuthorName))
+	if e.Desc != "" {
+		b.WriteString("<p>" + strings.ReplaceAll(html.EscapeString(e.Desc), "\n", "<br/>") + "</p>")
+	}
+	return b.String()
+}
+
+// feedFollows selects the follows a feed covers: all of them, or one group
+// (tagid -10 = special follows, 0 = default group).
+func feedFollows(userID uint, tagIDStr string) ([]model.Following, string, bool) {
+	var follows []model.Following
+	if tagIDStr == "" {
+		database.DB.Where("user_id = ? AND attribute IN ?", userID, model.RelationFollowed).Find(&follows)
+		return follows, "", true
+	}
+
+	tagID, err := strconv.ParseInt(tagIDStr, 10, 64)
+	if err != nil {
+		return nil, "", false
+	}
+	switch tagID {
+	case -10:
+		database.DB.Where("user_id = ? AND attribute = ? AND is_special = 1", userID, model.RelationFollow).
+			Find(&follows)
+		return follows, "特别关注", true
+	case 0:
+		defaultGroupQuery(userID).Find(&follows)
+		return follows, "默认分组", true
+	}
+
+	var tag model.FollowTag
+	if database.DB.Where("user_id = ? AND tag_id = ?", userID, tagID).First(&tag).Error != nil {
+		return nil, "", false
+	}
+	var mids []int64
+	database.DB.Model(&model.FollowTagMember{}).
+		Where("user_id = ? AND tag_id = ?", userID, tagID).Pluck("follow_mid", &mids)
+	if len(mids) > 0 {
+		database.DB.Where("user_id = ? AND mid IN ? AND attribute IN ?", userID, mids, model.RelationFollowed).
+			Find(&follows)
+	}
+	return follows, tag.Name, true
+}
+
+// feedEntries builds the newest entries of the merged follow feed from the
+// stored crawl; descriptions are added separately by fillFeedDescs.
+func feedEntries(follows []model.Following, limit int) []feedEntry {
+	names := make(map[int64]string, len(follows))
+	for _, f := range follows {
+		names[f.Mid] = f.Name
+	}
+
+	videos := followedVideos(follows)
+	if len(videos) > limit {
+		videos = videos[:limit]
+	}
+	entries := make([]feedEntry, 0, len(videos))
+	for _, v := range videos {
+		e := feedEntry{
+			Aid:        v.Aid,
+			Bvid:       v.Bvid,
+			Title:      v.Title,
+			Cover:      v.Pic,
+			Duration:   v.Duration,
+			Pubdate:    v.Pubdate,
+			AuthorMid:  v.OwnerMid,
+			AuthorName: names[v.OwnerMid],
+		}
+		entries = append(entries, e)
+	}
+	return entries
+}
+
+// fillFeedDescs sets the description of the newest feedDescLimit entries
+// from the (cached) video info.
+func fillFeedDescs(entries []feedEntry) {
+	if len(entries) > feedDescLimit {
+		entries = entries[:feedDescLimit]
+	}
+	var wg sync.WaitGroup
+	sem := make(chan struct{}, feedDescConcurrency)
+	for i := range entries {
+		wg.Add(1)
+		sem <- struct{}{}
+		go func(e *feedEntry) {
+			defer func() { <-sem; wg.Done() }()
+			if info, err := bilibili.FetchVideoInfo(e.Aid, e.Bvid); err == nil && !info.Invalid {
+				e.Desc = info.Desc
+			}
+		}(&entries[i])
+	}
+	wg.Wait()
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/feed/token  — 订阅源地址 (reset=1 换新 token)
+// ---------------------------------------------------------------------------
+
+func FeedToken(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+	reset := c.Query("reset") == "1" || c.Query("reset") == "true"
+
+	var user model.User
+	if err := database.DB.First(&user, userID).Error; err != nil {
+		response.Error(c, 404, -404, "user not found")
+		return
+	}
+	if user.FeedToken == "" || reset {
+		token, err := newShareToken()
+		if err != nil {
+			response.InternalError(c, "failed to create feed token")
+			return
+		}
+		user.FeedToken = token
+		database.DB.Model(&user).Update("feed_token", user.FeedToken)
+	}
+
+	base := requestBaseURL(c) + "/feed/" + user.FeedToken
+	response.Success(c, gin.H{
+		"token": user.FeedToken,
+		"rss":   base + "?format=rss",
+		"atom":  base + "?format=atom",
+		"json":  base + "?format=json",
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /feed/:token  — 关注动态订阅源（无需登录，token 鉴权）
+// ---------------------------------------------------------------------------
+
+// FollowFeed serves the followed-UP video feed to feed readers. Query:
+// format (rss, atom or json), tagid (one follow group) and limit.
+func FollowFeed(c *gin.Context) {
+	token := c.Param("token")
+	var user model.User
+	if token == "" || database.DB.Where("feed_token = ?", token).First(&user).Error != nil {
+		response.Error(c, 404, -404, "feed not found")
+		return
+	}
+
+	follows, group, ok := feedFollows(user.ID, c.Query("tagid"))
+	if !ok {
+		response.Error(c, 404, -404, "follow group not found")
+		return
+	}
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", strconv.Itoa(feedDefaultLimit)))
+	if limit < 1 || limit > feedMaxLimit {
+		limit = feedDefaultLimit
+	}
+
+	entries := feedEntries(follows, limit)
+
+	// Readers poll; let them skip unchanged feeds
+	updated := time.Now()
+	if len(entries) > 0 {
+		updated = time.Unix(entries[0].Pubdate, 0)
+		if since, err := http.ParseTime(c.GetHeader("If-Modified-Since")); err == nil && !updated.After(since) {
+			c.Status(http.StatusNotModified)
+			return
+		}
+		c.Header("Last-Modified", updated.UTC().Format(http.TimeFormat))
+	}
+	fillFeedDescs(entries)
+
+	title := user.Username + " 的关注动态"
+	if group != "" {
+		title += " · " + group
+	}
+	selfURL := requestBaseURL(c) + c.Request.URL.RequestURI()
+
+	switch c.DefaultQuery("format", "rss") {
+	case "atom":
+		writeAtom(c, title, selfURL, updated, entries)
+	case "json":
+		writeJSONFeed(c, title, selfURL, entries)
+	default:
+		writeRSS(c, title, selfURL, updated, entries)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// RSS 2.0
+// ---------------------------------------------------------------------------
+
+type rssFeed struct {
+	XMLName xml.Name   `xml:"rss"`
+	Version string     `xml:"version,attr"`
+	DC      string     `xml:"xmlns:dc,attr"`
+	Atom    string     `xml:"xmlns:atom,attr"`
+	Channel rssChannel `xml:"channel"`
+}
+
+type rssChannel struct {
+	Title         string    `xml:"title"`
+	Link          string    `xml:"link"`
+	Self          rssSelf   `xml:"atom:link"`
+	Description   string    `xml:"description"`
+	LastBuildDate string    `xml:"lastBuildDate"`
+	Items         []rssItem `xml:"item"`
+}
+
+type rssSelf struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr"`
+	Type string `xml:"type,attr"`
+}
+
+type rssItem struct {
+	Title       string        `xml:"title"`
+	Link        string        `xml:"link"`
+	GUID        rssGUID       `xml:"guid"`
+	PubDate     string        `xml:"pubDate"`
+	Creator     string        `xml:"dc:creator,omitempty"`
+	Description string        `xml:"description"`
+	Enclosure   *rssEnclosure `xml:"enclosure,omitempty"`
+}
+
+type rssGUID struct {
+	IsPermaLink string `xml:"isPermaLink,attr"`
+	Value       string `xml:",chardata"`
+}
+
+type rssEnclosure struct {
+	URL    string `xml:"url,attr"`
+	Type   string `xml:"type,attr"`
+	Length int    `xml:"length,attr"`
+}
+
+func writeRSS(c *gin.Context, title, selfURL string, updated time.Time, entries []feedEntry) {
+	feed := rssFeed{
+		Version: "2.0",
+		DC:      "http://purl.org/dc/elements/1.1/",
+		Atom:    "http://www.w3.org/2005/Atom",
+		Channel: rssChannel{
+			Title:         title,
+			Link:          "https://t.bilibili.com",
+			Self:          rssSelf{Href: selfURL, Rel: "self", Type: "application/rss+xml"},
+			Description:   title,
+			LastBuildDate: updated.Format(time.RFC1123Z),
+			Items:         make([]rssItem, 0, len(entries)),
+		},
+	}
+	for i := range entries {
+		e := &entries[i]
+		item := rssItem{
+			Title:       e.Title,
+			Link:        e.link(),
+			GUID:        rssGUID{IsPermaLink: "true", Value: e.link()},
+			PubDate:     time.Unix(e.Pubdate, 0).Format(time.RFC1123Z),
+			Creator:     e.AuthorName,
+			Description: e.contentHTML(),
+		}
+		if e.Cover != "" {
+			item.Enclosure = &rssEnclosure{URL: model.ImageURL(e.Cover), Type: "image/jpeg"}
+		}
+		feed.Channel.Items = append(feed.Channel.Items, item)
+	}
+	writeXML(c, "application/rss+xml; charset=utf-8", feed)
+}
+
+// ---------------------------------------------------------------------------
+// Atom
+// ---------------------------------------------------------------------------
+
+type atomFeed struct {
+	XMLName xml.Name    `xml:"http://www.w3.org/2005/Atom feed"`
+	ID      string      `xml:"id"`
+	Title   string      `xml:"title"`
+	Updated string      `xml:"updated"`
+	Links   []atomLink  `xml:"link"`
+	Entries []atomEntry `xml:"entry"`
+}
+
+type atomLink struct {
+	Href string `xml:"href,attr"`
+	Rel  string `xml:"rel,attr,omitempty"`
+	Type string `xml:"type,attr,omitempty"`
+}
+
+type atomEntry struct {
+	ID        string     `xml:"id"`
+	Title     string     `xml:"title"`
+	Updated   string     `xml:"updated"`
+	Published string     `xml:"published"`
+	Links     []atomLink `xml:"link"`
+	Author    atomAuthor `xml:"author"`
+	Content   atomText   `xml:"content"`
+}
+
+type atomAuthor struct {
+	Name string `xml:"name"`
+	URI  string `xml:"uri"`
+}
+
+type atomText struct {
+	Type string `xml:"type,attr"`
+	Body string `xml:",chardata"`
+}
+
+func writeAtom(c *gin.Context, title, selfURL string, updated time.Time, entries []feedEntry) {
+	feed := atomFeed{
+		ID:      selfURL,
+		Title:   title,
+		Updated: updated.Format(time.RFC3339),
+		Links: []atomLink{
+			{Href: selfURL, Rel: "self", Type: "application/atom+xml"},
+			{Href: "https://t.bilibili.com", Rel: "alternate"},
+		},
+		Entries: make([]atomEntry, 0, len(entries)),
+	}
+	for i := range entries {
+		e := &entries[i]
+		published := time.Unix(e.Pubdate, 0).Format(time.RFC3339)
+		links := []atomLink{{Href: e.link(), Rel: "alternate", Type: "text/html"}}
+		if e.Cover != "" {
+			links = append(links, atomLink{Href: model.ImageURL(e.Cover), Rel: "enclosure", Type: "image/jpeg"})
+		}
+		feed.Entries = append(feed.Entries, atomEntry{
+			ID:        e.link(),
+			Title:     e.Title,
+			Updated:   published,
+			Published: published,
+			Links:     links,
+			Author:    atomAuthor{Name: e.AuthorName, URI: e.authorLink()},
+			Content:   atomText{Type: "html", Body: e.contentHTML()},
+		})
+	}
+	writeXML(c, "application/atom+xml; charset=utf-8", feed)
+}
+
+func writeXML(c *gin.Context, contentType string, v interface{}) {
+	body, err := xml.MarshalIndent(v, "", "  ")
+	if err != nil {
+		response.InternalError(c, "failed to render feed")
+		return
+	}
+	c.Data(http.StatusOK, contentType, append([]byte(xml.Header), body...))
+}
+
+// ---------------------------------------------------------------------------
+// JSON Feed 1.1
+// ---------------------------------------------------------------------------
+
+func writeJSONFeed(c *gin.Context, title, selfURL string, entries []feedEntry) {
+	items := make([]gin.H, 0, len(entries))
+	for i := range entries {
+		e := &entries[i]
+		item := gin.H{
+			"id":             e.link(),
+			"url":            e.link(),
+			"title":          e.Title,
+			"content_html":   e.contentHTML(),
+			"summary":        e.Desc,
+			"date_published": time.Unix(e.Pubdate, 0).Format(time.RFC3339),
+			"authors": []gin.H{{
+				"name": e.AuthorName,
+				"url":  e.authorLink(),
+			}},
+			"_bilibili": gin.H{
+				"aid":      e.Aid,
+				"bvid":     e.Bvid,
+				"duration": e.Duration,
+			},
+		}
+		if e.Cover != "" {
+			item["image"] = model.ImageURL(e.Cover)
+		}
+		items = append(items, item)
+	}
+
+	body, err := json.Marshal(gin.H{
+		"version":       "https://jsonfeed.org/version/1.1",
+		"title":         title,
+		"home_page_url": "https://t.bilibili.com",
+		"feed_url":      selfURL,
+		"items":         items,
+	})
+	if err != nil {
+		response.InternalError(c, "failed to render feed")
+		return
+	}
+	c.Data(http.StatusOK, "application/feed+json; charset=utf-8", body)
+}
@@ -26,14 +26,91 @@ func nextTagID(userID uint) int64 {
 	return maxID + 1
 }
 
-// refreshTagCount recalculates count for a tag.
-func refreshTagCount(userID uint, tagID int64) {
+// refreshTagCount recalculates count for a tag. Pass the transaction that
+// changed its members, or database.DB.
+func refreshTagCount(db *gorm.DB, userID uint, tagID int64) error {
 	var cnt int64
-	database.DB.Model(&model.FollowTagMember{}).
-		Where("user_id = ? AND tag_id = ?", userID, tagID).Count(&cnt)
-	database.DB.Model(&model.FollowTag{}).
+	if err := db.Model(&model.FollowTagMember{}).
+		Where("user_id = ? AND tag_id = ?", userID, tagID).Count(&cnt).Error; err != nil {
+		return err
+	}
+	return db.Model(&model.FollowTag{}).
 		Where("user_id = ? AND tag_id = ?", userID, tagID).
-		Update("count", cnt)
+		Update("count", cnt).Error
+}
+
+// userTagIDs keeps the ids of tags the user actually owns. The virtual
+// groups (0 = default, -10 = special follow) are never stored as members.
+func userTagIDs(db *gorm.DB, userID uint, tagIDs []int64) []int64 {
+	if len(tagIDs) == 0 {
+		return nil
+	}
+	var owned []int64
+	db.Model(&model.FollowTag{}).
+		Where("user_id = ? AND tag_id IN ?", userID, tagIDs).
+		Pluck("tag_id", &owned)
+	return owned
+}
+
+// publicFollowMids keeps the fids the user publicly follows; only those can
+// be grouped.
+func publicFollowMids(db *gorm.DB, userID uint, fids []int64) []int64 {
+	if len(fids) == 0 {
+		return nil
+	}
+	var mids []int64
+	db.Model(&model.Following{}).
+		Where("user_id = ? AND mid IN ? AND attribute = ?", userID, fids, model.RelationFollow).
+		Pluck("mid", &mids)
+	return mids
+}
+
+// addTagMembers puts fids into each tag, skipping existing memberships.
+func addTagMembers(tx *gorm.DB, userID uint, tagIDs, fids []int64) error {
+	for _, tagID := range tagIDs {
+		for _, fid := range fids {
+			var existing model.FollowTagMember
+			err := tx.Where("user_id = ? AND tag_id = ? AND follow_mid = ?", userID, tagID, fid).
+				First(&existing).Error
+			if err == gorm.ErrRecordNotFound {
+				err = tx.Create(&model.FollowTagMember{
+					UserID:    userID,
+					TagID:     tagID,
+					FollowMid: fid,
+				}).Error
+			}
+			if err != nil {
+				return err
+			}
+		}
+		if err := refreshTagCount(tx, userID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// removeTagMembers takes fids out of each tag. Users left without any tag
+// fall back to the default group.
+func removeTagMembers(tx *gorm.DB, userID uint, tagIDs, fids []int64) error {
+	for _, tagID := range tagIDs {
+		if err := tx.Where("user_id = ? AND tag_id = ? AND follow_mid IN ?", userID, tagID, fids).
+			Delete(&model.FollowTagMember{}).Error; err != nil {
+			return err
+		}
+		if err := refreshTagCount(tx, userID, tagID); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// defaultGroupQuery selects public follows that belong to no tag (tagid 0).
+func defaultGroupQuery(userID uint) *gorm.DB {
+	return database.DB.Model(&model.Following{}).
+		Where("user_id = ? AND attribute = ?", userID, model.RelationFollow).
+		Where("mid NOT IN (?)", database.DB.Model(&model.FollowTagMember{}).
+			Select("follow_mid").Where("user_id = ?", userID))
 }
 
 // ---------------------------------------------------------------------------
@@ -55,7 +132,7 @@ func Relation(c *gin.Context) {
 	special := 0
 	if database.DB.Where("user_id = ? AND mid = ?", userID, fid).First(&f).Error == nil {
 		attribute = int(f.Attribute)
-		if f.IsSpecial == 1 {
+		if f.IsSpecial == 1 && f.Attribute == model.RelationFollow {
 			special = 1
 		}
 	}
@@ -88,17 +165,26 @@ func Followings(c *gin.Context) {
 	offset := (pn - 1) * ps
 
 	var total int64
-	database.DB.Model(&model.Following{}).Where("user_id = ?", userID).Count(&total)
+	database.DB.Model(&model.Following{}).
+		Where("user_id = ? AND attribute = ?", userID, model.RelationFollow).Count(&total)
 
-	orderClause := "m_time DESC"
+	var follows []model.Following
 	if orderType == "attention" {
-		orderClause = "is_special DESC, m_time DESC"
+		// 最常访问: special follows, then by interaction score. The score
+		// isn't a column, so the whole list is sorted before paging.
+		database.DB.Where("user_id = ? AND attribute = ?", userID, model.RelationFollow).
+			Order("m_time DESC").Find(&follows)
+		sortByInteraction(follows, interactionScores(userID), true)
+		if offset < 0 || offset >= len(follows) {
+			follows = nil
+		} else {
+			follows = follows[offset:min(offset+ps, len(follows))]
+		}
+	} else {
+		database.DB.Where("user_id = ? AND attribute = ?", userID, model.RelationFollow).
+			Order("m_time DESC").Offset(offset).Limit(ps).Find(&follows)
 	}
 
-	var follows []model.Following
-	database.DB.Where("user_id = ?", userID).
-		Order(orderClause).Offset(offset).Limit(ps).Find(&follows)
-
 	list := make([]map[string]interface{}, 0, len(follows))
 	for _, f := range follows {
 		list = append(list, f.ToBiliJSON())
@@ -111,6 +197,52 @@ func Followings(c *gin.Context) {
 	})
 }
 
+// ---------------------------------------------------------------------------
+// GET /x/relation/whispers  — 悄悄关注列表
+// GET /x/relation/blacks    — 黑名单
+// ---------------------------------------------------------------------------
+
+func Whispers(c *gin.Context) {
+	relationList(c, model.RelationWhisper)
+}
+
+func Blacks(c *gin.Context) {
+	relationList(c, model.RelationBlack)
+}
+
+func relationList(c *gin.Context, attribute int) {
+	userID := middleware.GetUserID(c)
+
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "50"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 100 {
+		ps = 50
+	}
+
+	query := database.DB.Model(&model.Following{}).
+		Where("user_id = ? AND attribute = ?", userID, attribute)
+
+	var total int64
+	query.Count(&total)
+
+	var rows []model.Following
+	query.Order("m_time DESC").Offset((pn - 1) * ps).Limit(ps).Find(&rows)
+
+	list := make([]map[string]interface{}, 0, len(rows))
+	for _, f := range rows {
+		list = append(list, f.ToBiliJSON())
+	}
+
+	response.Success(c, gin.H{
+		"list":       list,
+		"re_version": 0,
+		"total":      total,
+	})
+}
+
 // ---------------------------------------------------------------------------
 // GET /x/relation/followings/search
 // ---------------------------------------------------------------------------
@@ -123,7 +255,8 @@ func FollowingsSearch(c *gin.Context) {
 	name := c.DefaultQuery("name", "")
 	offset := (pn - 1) * ps
 
-	query := database.DB.Model(&model.Following{}).Where("user_id = ?", userID)
+	query := database.DB.Model(&model.Following{}).
+		Where("user_id = ? AND attribute = ?", userID, model.RelationFollow)
 	if name != "" {
 		query = query.Where("name LIKE ?", "%"+name+"%")
 	}
@@ -146,9 +279,76 @@ func FollowingsSearch(c *gin.Context) {
 }
 
 // ---------------------------------------------------------------------------
-// POST /x/relation/modify  — follow / unfollow / block / unblock
+// POST /x/relation/modify  — follow / unfollow / whisper / block / unblock
 // ---------------------------------------------------------------------------
 
+// setRelation puts fid into the given relation with the user, creating the
+// row if needed. Only public follows keep tags and special follow.
+func setRelation(userID uint, fid int64, attribute int, uname, face string, now int64) {
+	var existing model.Following
+	if database.DB.Where("user_id = ? AND mid = ?", userID, fid).
+		First(&existing).Error == gorm.ErrRecordNotFound {
+		database.DB.Create(&model.Following{
+			UserID:    userID,
+			Mid:       fid,
+			Name:      uname,
+			Face:      face,
+			Attribute: attribute,
+			MTime:     now,
+		})
+		return
+	}
+
+	updates := map[string]interface{}{}
+	if existing.Attribute != attribute {
+		updates["attribute"] = attribute
+		updates["m_time"] = now
+	}
+	if uname != "" {
+		updates["name"] = uname
+	}
+	if face != "" {
+		updates["face"] = face
+	}
+	if attribute != model.RelationFollow {
+		updates["is_special"] = 0
+		clearFollowTags(userID, fid)
+	}
+	if len(updates) > 0 {
+		database.DB.Model(&existing).Updates(updates)
+	}
+}
+
+// dropRelation removes fid if it is currently in the given relation.
+func dropRelation(userID uint, fid int64, attribute int) {
+	if attribute == model.RelationFollow {
+		clearFollowTags(userID, fid)
+	}
+	database.DB.Where("user_id = ? AND mid = ? AND attribute = ?", userID, fid, attribute).
+		Delete(&model.Following{})
+}
+
+// clearFollowTags removes fid from all of the user's tags.
+func clearFollowTags(userID uint, fid int64) {
+	var members []model.FollowTagMember
+	database.DB.Where("user_id = ? AND follow_mid = ?", userID, fid).Find(&members)
+	if len(members) == 0 {
+		return
+	}
+	database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Where("user_id = ? AND follow_mid = ?", userID, fid).
+			Delete(&model.FollowTagMember{}).Error; err != nil {
+			return err
+		}
+		for _, m := range members {
+			if err := refreshTagCount(tx, userID, m.TagID); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
 func RelationMod(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 
@@ -164,54 +364,22 @@ func RelationMod(c *gin.Context) {
 
 	now := time.Now().Unix()
 
+	uname := c.PostForm("uname")
+	face := c.PostForm("face")
+
 	switch act {
 	case 1: // follow
-		uname := c.PostForm("uname")
-		face := c.PostForm("face")
-		var existing model.Following
-		if database.DB.Where("user_id = ? AND mid = ?", userID, fid).
-			First(&existing).Error == gorm.ErrRecordNotFound {
-			f := model.Following{
-				UserID:    userID,
-				Mid:       fid,
-				Name:      uname,
-				Face:      face,
-				Attribute: 2,
-				MTime:     now,
-			}
-			database.DB.Create(&f)
-		} else {
-			// Update name/face if provided
-			updates := map[string]interface{}{}
-			if uname != "" {
-				updates["name"] = uname
-			}
-			if face != "" {
-				updates["face"] = face
-			}
-			if len(updates) > 0 {
-				database.DB.Model(&model.Following{}).
-					Where("user_id = ? AND mid = ?", userID, fid).
-					Updates(updates)
-			}
-		}
+		setRelation(userID, fid, model.RelationFollow, uname, face, now)
 	case 2: // unfollow
-		// Remove from all tags
-		database.DB.Where("user_id = ? AND follow_mid = ?", userID, fid).
-			Delete(&model.FollowTagMember{})
-		database.DB.Where("user_id = ? AND mid = ?", userID, fid).
-			Delete(&model.Following{})
-		// Refresh tag counts
-		var tags []model.FollowTag
-		database.DB.Where("user_id = ?", userID).Find(&tags)
-		for _, t := range tags {
-			refreshTagCount(userID, t.TagID)
-		}
-	case 5, 6: // block / unblock — we just do follow/unfollow locally
-		if act == 5 {
-			database.DB.Where("user_id = ? AND mid = ?", userID, fid).
-				Delete(&model.Following{})
-		}
+		dropRelation(userID, fid, model.RelationFollow)
+	case 3: // whisper follow
+		setRelation(userID, fid, model.RelationWhisper, uname, face, now)
+	case 4: // cancel whisper follow
+		dropRelation(userID, fid, model.RelationWhisper)
+	case 5: // block — replaces any follow
+		setRelation(userID, fid, model.RelationBlack, uname, face, now)
+	case 6: // unblock
+		dropRelation(userID, fid, model.RelationBlack)
 	default:
 		response.BadRequest(c, "invalid act")
 		return
@@ -241,6 +409,16 @@ func FollowTags(c *gin.Context) {
 		"tip":   "",
 	})
 
+	// Followed users without any tag form the default group (tagid = 0)
+	var defaultCnt int64
+	defaultGroupQuery(userID).Count(&defaultCnt)
+	list = append(list, map[string]interface{}{
+		"tagid": 0,
+		"name":  "默认分组",
+		"count": defaultCnt,
+		"tip":   "",
+	})
+
 	for _, t := range tags {
 		list = append(list, t.ToBiliJSON())
 	}
@@ -277,6 +455,21 @@ func FollowTagMembers(c *gin.Context) {
 		return
 	}
 
+	if tagID == 0 {
+		// Default group: public follows without a tag
+		var follows []model.Following
+		defaultGroupQuery(userID).Order("m_time DESC").Offset(offset).Limit(ps).Find(&follows)
+
+		list := make([]map[string]interface{}, 0, len(follows))
+		for _, f := range follows {
+			list = append(list, f.ToBiliJSON())
+		}
+
+		// Client expects data to be a plain array
+		response.Success(c, list)
+		return
+	}
+
 	// Normal tag
 	var members []model.FollowTagMember
 	database.DB.Where("user_id = ? AND tag_id = ?", userID, tagID).
@@ -392,22 +585,104 @@ func AddUsersToTag(c *gin.Context) {
 		return
 	}
 
-	fids := parseIntList(fidsStr)
-	tagids := parseIntList(tagidsStr)
+	// tagids = 0 (default group) adds nothing
+	tagids := userTagIDs(database.DB, userID, parseIntList(tagidsStr))
+	fids := publicFollowMids(database.DB, userID, parseIntList(fidsStr))
+	if len(fids) == 0 {
+		response.Success(c, nil)
+		return
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return addTagMembers(tx, userID, tagids, fids)
+	}); err != nil {
+		response.InternalError(c, "failed to add users to tags")
+		return
+	}
 
-	for _, tagID := range tagids {
-		for _, fid := range fids {
-			var existing model.FollowTagMember
-			if database.DB.Where("user_id = ? AND tag_id = ? AND follow_mid = ?", userID, tagID, fid).
-				First(&existing).Error == gorm.ErrRecordNotFound {
-				database.DB.Create(&model.FollowTagMember{
-					UserID:    userID,
-					TagID:     tagID,
-					FollowMid: fid,
-				})
-			}
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/relation/tags/copyUsers  — 复制到分组 (keeps existing groups)
+// ---------------------------------------------------------------------------
+
+func CopyUsersToTag(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	fidsStr := c.PostForm("fids")
+	tagidsStr := c.PostForm("afterTagids")
+	if tagidsStr == "" {
+		tagidsStr = c.PostForm("tagids")
+	}
+	if fidsStr == "" || tagidsStr == "" {
+		response.BadRequest(c, "fids and afterTagids are required")
+		return
+	}
+
+	tagids := userTagIDs(database.DB, userID, parseIntList(tagidsStr))
+	fids := publicFollowMids(database.DB, userID, parseIntList(fidsStr))
+	if len(fids) == 0 {
+		response.Success(c, nil)
+		return
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		return addTagMembers(tx, userID, tagids, fids)
+	}); err != nil {
+		response.InternalError(c, "failed to copy users")
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/relation/tags/moveUsers  — 移动到分组
+// ---------------------------------------------------------------------------
+
+// MoveUsersToTag takes fids out of beforeTagids and puts them into
+// afterTagids. afterTagids = 0 only removes them, leaving them in the
+// default group if no other tag remains.
+func MoveUsersToTag(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	fidsStr := c.PostForm("fids")
+	beforeStr := c.PostForm("beforeTagids")
+	afterStr := c.PostForm("afterTagids")
+	if fidsStr == "" || beforeStr == "" || afterStr == "" {
+		response.BadRequest(c, "fids, beforeTagids and afterTagids are required")
+		return
+	}
+
+	before := userTagIDs(database.DB, userID, parseIntList(beforeStr))
+	after := userTagIDs(database.DB, userID, parseIntList(afterStr))
+	fids := publicFollowMids(database.DB, userID, parseIntList(fidsStr))
+	if len(fids) == 0 {
+		response.Success(c, nil)
+		return
+	}
+
+	// Moving into a tag the user is already in must not drop them from it
+	stay := map[int64]bool{}
+	for _, id := range after {
+		stay[id] = true
+	}
+	remove := make([]int64, 0, len(before))
+	for _, id := range before {
+		if !stay[id] {
+			remove = append(remove, id)
 		}
-		refreshTagCount(userID, tagID)
+	}
+
+	if err := database.DB.Transaction(func(tx *gorm.DB) error {
+		if err := removeTagMembers(tx, userID, remove, fids); err != nil {
+			return err
+		}
+		return addTagMembers(tx, userID, after, fids)
+	}); err != nil {
+		response.InternalError(c, "failed to move users")
+		return
 	}
 
 	response.Success(c, nil)
@@ -428,7 +703,7 @@ func AddSpecial(c *gin.Context) {
 	}
 
 	database.DB.Model(&model.Following{}).
-		Where("user_id = ? AND mid = ?", userID, fid).
+		Where("user_id = ? AND mid = ? AND attribute = ?", userID, fid, model.RelationFollow).
 		Update("is_special", 1)
 
 	response.Success(c, nil)
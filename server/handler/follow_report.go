@@ -0,0 +1,263 @@
+package handler
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Follow list upkeep: suggestions, inactive follows and bulk changes
+// ===========================================================================
+
+// A favorite says more than a single view.
+const suggestFavWeight = 2.0
+
+// Bilibili caps batch relation changes at 50 accounts.
+const relationBatchMax = 50
+
+type upActivity struct {
+	AuthorMid int64
+	Cnt       int64
+	Last      int64
+}
+
+// knownProfile finds a name and avatar for an account the user doesn't
+// follow yet, from what they watched or favorited.
+func knownProfile(userID uint, mid int64) (string, string) {
+	var h model.WatchHistory
+	if database.DB.Select("author_name", "author_face").
+		Where("user_id = ? AND author_mid = ? AND author_name <> ''", userID, mid).
+		Order("view_at DESC").First(&h).Error == nil {
+		return h.AuthorName, h.AuthorFace
+	}
+	var r model.FavResource
+	if database.DB.Select("upper_name").
+		Where("user_id = ? AND upper_mid = ? AND upper_name <> ''", userID, mid).
+		Order("fav_time DESC").First(&r).Error == nil {
+		return r.UpperName, ""
+	}
+	return "", ""
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/relation/suggest  — 常看但未关注的 UP
+// ---------------------------------------------------------------------------
+
+func FollowSuggest(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if ps < 1 || ps > 100 {
+		ps = 20
+	}
+	// Ignore UPs seen only once or twice in passing
+	minCount, _ := strconv.Atoi(c.DefaultQuery("min_count", "3"))
+
+	var watched []upActivity
+	database.DB.Model(&model.WatchHistory{}).
+		Select("author_mid, COUNT(*) AS cnt, MAX(view_at) AS last").
+		Where("user_id = ? AND business = ? AND author_mid > 0", userID, "archive").
+		Group("author_mid").Scan(&watched)
+
+	var faved []upActivity
+	database.DB.Model(&model.FavResource{}).
+		Select("upper_mid AS author_mid, COUNT(*) AS cnt, MAX(fav_time) AS last").
+		Where("user_id = ? AND upper_mid > 0", userID).
+		Group("upper_mid").Scan(&faved)
+
+	// Anyone the user already has a relation with, including blocks
+	related := map[int64]bool{}
+	var relatedMids []int64
+	database.DB.Model(&model.Following{}).Where("user_id = ?", userID).Pluck("mid", &relatedMids)
+	for _, mid := range relatedMids {
+		related[mid] = true
+	}
+
+	type suggestion struct {
+		mid        int64
+		watchCount int64
+		favCount   int64
+		lastSeen   int64
+		score      float64
+	}
+	byMid := map[int64]*suggestion{}
+	get := func(mid int64) *suggestion {
+		if byMid[mid] == nil {
+			byMid[mid] = &suggestion{mid: mid}
+		}
+		return byMid[mid]
+	}
+	for _, w := range watched {
+		if !related[w.AuthorMid] {
+			s := get(w.AuthorMid)
+			s.watchCount, s.lastSeen = w.Cnt, max(s.lastSeen, w.Last)
+		}
+	}
+	for _, f := range faved {
+		if !related[f.AuthorMid] {
+			s := get(f.AuthorMid)
+			s.favCount, s.lastSeen = f.Cnt, max(s.lastSeen, f.Last)
+		}
+	}
+
+	scores := interactionScores(userID)
+	list := make([]*suggestion, 0, len(byMid))
+	for _, s := range byMid {
+		if s.watchCount+s.favCount < int64(minCount) {
+			continue
+		}
+		s.score = scores[s.mid] + suggestFavWeight*float64(s.favCount)
+		list = append(list, s)
+	}
+	sort.Slice(list, func(i, j int) bool {
+		if list[i].score != list[j].score {
+			return list[i].score > list[j].score
+		}
+		return list[i].lastSeen > list[j].lastSeen
+	})
+	if len(list) > ps {
+		list = list[:ps]
+	}
+
+	items := make([]gin.H, 0, len(list))
+	for _, s := range list {
+		name, face := knownProfile(userID, s.mid)
+		items = append(items, gin.H{
+			"mid":         s.mid,
+			"uname":       name,
+			"face":        model.ImageURL(face),
+			"watch_count": s.watchCount,
+			"fav_count":   s.favCount,
+			"last_seen":   s.lastSeen,
+			"score":       s.score,
+		})
+	}
+
+	response.Success(c, gin.H{"list": items})
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/relation/inactive  — 长期未更新或已注销的关注
+// ---------------------------------------------------------------------------
+
+// FollowInactive lists follows whose account is closed, or whose newest
+// crawled video is older than `months`. UPs with no crawled videos at all
+// are left out: the crawl can't tell "never uploaded" from "not fetched yet".
+func FollowInactive(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	months, _ := strconv.Atoi(c.DefaultQuery("months", "6"))
+	if months < 1 {
+		months = 6
+	}
+	cutoff := time.Now().AddDate(0, -months, 0).Unix()
+
+	var follows []model.Following
+	database.DB.Where("user_id = ? AND attribute IN ?", userID, model.RelationFollowed).
+		Order("m_time ASC").Find(&follows)
+	if len(follows) == 0 {
+		response.Success(c, gin.H{"list": []gin.H{}, "months": months})
+		return
+	}
+
+	mids := make([]int64, 0, len(follows))
+	for _, f := range follows {
+		mids = append(mids, f.Mid)
+	}
+	var latest []struct {
+		Mid  int64
+		Last int64
+	}
+	database.DB.Model(&model.SpaceArchive{}).
+		Select("mid, MAX(pubdate) AS last").
+		Where("mid IN ?", mids).Group("mid").Scan(&latest)
+	lastPub := make(map[int64]int64, len(latest))
+	for _, l := range latest {
+		lastPub[l.Mid] = l.Last
+	}
+
+	items := make([]gin.H, 0)
+	for i := range follows {
+		f := &follows[i]
+		last, crawled := lastPub[f.Mid]
+		reason := ""
+		switch {
+		case f.Deactivated:
+			reason = "deactivated"
+		case crawled && last < cutoff:
+			reason = "inactive"
+		default:
+			continue
+		}
+		item := f.ToBiliJSON()
+		item["reason"] = reason
+		item["last_pubdate"] = last
+		items = append(items, item)
+	}
+
+	response.Success(c, gin.H{"list": items, "months": months})
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/relation/batch/modify  — 批量关注 / 取关
+// ---------------------------------------------------------------------------
+
+// RelationBatchMod applies act (1 follow, 2 unfollow, 3 whisper follow,
+// 4 cancel whisper) to every account in fids, so the suggestion and inactive
+// reports can be acted on in one request.
+func RelationBatchMod(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	act, _ := strconv.Atoi(c.PostForm("act"))
+	fids := parseIntList(c.PostForm("fids"))
+	if len(fids) == 0 {
+		response.BadRequest(c, "fids is required")
+		return
+	}
+	if len(fids) > relationBatchMax {
+		response.BadRequest(c, "too many fids (max "+strconv.Itoa(relationBatchMax)+")")
+		return
+	}
+
+	now := time.Now().Unix()
+	failed := make([]int64, 0)
+	for _, fid := range fids {
+		if fid <= 0 {
+			failed = append(failed, fid)
+			continue
+		}
+		switch act {
+		case 1, 3:
+			attribute := model.RelationFollow
+			if act == 3 {
+				attribute = model.RelationWhisper
+			}
+			var existing model.Following
+			if database.DB.Where("user_id = ? AND mid = ? AND attribute = ?", userID, fid, model.RelationBlack).
+				First(&existing).Error == nil {
+				// Blocked accounts must be unblocked explicitly
+				failed = append(failed, fid)
+				continue
+			}
+			uname, face := knownProfile(userID, fid)
+			setRelation(userID, fid, attribute, uname, face, now)
+		case 2:
+			dropRelation(userID, fid, model.RelationFollow)
+		case 4:
+			dropRelation(userID, fid, model.RelationWhisper)
+		default:
+			response.BadRequest(c, "invalid act")
+			return
+		}
+	}
+
+	response.Success(c, gin.H{"failed_fids": failed})
+}
@@ -1,7 +1,9 @@
 package handler
 
 import (
+	"fmt"
 	"strconv"
+	"strings"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -37,9 +39,9 @@ func HistoryList(c *gin.Context) {
 	if viewAt > 0 {
 		query = query.Where("view_at < ?", viewAt)
 	} else if maxOid > 0 {
-		// Fallback: use max (aid) as cursor
+		// Fallback: use max (oid) + business as cursor
 		var cursor model.WatchHistory
-		if err := database.DB.Where("user_id = ? AND aid = ?", userID, maxOid).
+		if err := historyByOid(userID, c.DefaultQuery("business", typ), maxOid).
 			First(&cursor).Error; err == nil {
 			query = query.Where("view_at < ?", cursor.ViewAt)
 		}
@@ -65,7 +67,7 @@ func HistoryList(c *gin.Context) {
 		cursorData = map[string]interface{}{
 			"max":      last.Aid,
 			"view_at":  last.ViewAt,
-			"business": typ,
+			"business": last.Business,
 			"ps":       ps,
 		}
 	} else {
@@ -80,10 +82,41 @@ func HistoryList(c *gin.Context) {
 	response.Success(c, gin.H{
 		"cursor": cursorData,
 		"list":   list,
-		"tab":    []interface{}{},
+		"tab":    userHistoryTabs(userID),
 	})
 }
 
+// historyTabs lists every business the history list can be filtered by, in
+// the order the official client shows them.
+var historyTabs = []struct{ Type, Name string }{
+	{"archive", "视频"},
+	{"pgc", "番剧"},
+	{"cheese", "课程"},
+	{"live", "直播"},
+	{"article", "专栏"},
+}
+
+// userHistoryTabs returns the tabs for the businesses the user actually has
+// history entries for.
+func userHistoryTabs(userID uint) []gin.H {
+	var businesses []string
+	database.DB.Model(&model.WatchHistory{}).Where("user_id = ?", userID).
+		Distinct("business").Pluck("business", &businesses)
+
+	has := make(map[string]bool, len(businesses))
+	for _, b := range businesses {
+		has[b] = true
+	}
+
+	tabs := make([]gin.H, 0, len(businesses))
+	for _, t := range historyTabs {
+		if has[t.Type] {
+			tabs = append(tabs, gin.H{"type": t.Type, "name": t.Name})
+		}
+	}
+	return tabs
+}
+
 // ---------------------------------------------------------------------------
 // GET /x/web-interface/history/search  — 搜索历史记录
 // ---------------------------------------------------------------------------
@@ -163,29 +196,154 @@ func DelHistory(c *gin.Context) {
 		return
 	}
 
-	// kid is the aid (oid) of the history entry
-	kid, _ := strconv.ParseInt(kidStr, 10, 64)
-	if kid == 0 {
-		// kid might be in "archive_123456" format — try to parse after underscore
-		// but for self-hosted, we just use the numeric aid
-		response.BadRequest(c, "invalid kid")
-		return
+	// kid is a comma-separated list of "business_oid" (e.g. archive_123,live_456).
+	// A bare numeric kid is treated as a video aid. Kids that no longer match
+	// an entry are fine: another device or a retry already removed them.
+	kids := strings.Split(kidStr, ",")
+	for _, kid := range kids {
+		if _, oid := parseHistoryKid(strings.TrimSpace(kid)); oid == 0 {
+			response.BadRequest(c, "invalid kid")
+			return
+		}
+	}
+	for _, kid := range kids {
+		business, oid := parseHistoryKid(strings.TrimSpace(kid))
+		deleteHistory(userID, historyByOid(userID, business, oid))
 	}
 
-	database.DB.Where("user_id = ? AND aid = ?", userID, kid).Delete(&model.WatchHistory{})
 	response.Success(c, nil)
 }
 
+// parseHistoryKid splits a "business_oid" kid. Business names may themselves
+// contain dashes (article-list), so split on the last underscore.
+func parseHistoryKid(kid string) (string, int64) {
+	business := "archive"
+	if i := strings.LastIndex(kid, "_"); i >= 0 {
+		business = kid[:i]
+		kid = kid[i+1:]
+	}
+	oid, _ := strconv.ParseInt(kid, 10, 64)
+	return business, oid
+}
+
+// videoBusinesses all key history entries by aid, so a lookup for any of them
+// matches the others. Live rooms and articles have their own id spaces.
+var videoBusinesses = []string{"archive", "pgc", "cheese"}
+
+// historyByOid scopes a WatchHistory query to the entry identified by
+// business and oid.
+func historyByOid(userID uint, business string, oid int64) *gorm.DB {
+	query := database.DB.Model(&model.WatchHistory{}).Where("user_id = ? AND aid = ?", userID, oid)
+	switch business {
+	case "live", "article", "article-list":
+		return query.Where("business = ?", business)
+	default:
+		return query.Where("business IN ?", videoBusinesses)
+	}
+}
+
+// historyPaused reports whether the user has paused history recording.
+func historyPaused(userID uint) bool {
+	var settings model.UserSettings
+	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err == nil {
+		return settings.HistoryPaused == 1
+	}
+	return false
+}
+
+// findHistoryEntry looks up the entry for a video or season. Bangumi and
+// course episodes collapse into one entry per season, so those are matched by
+// season_id before falling back to the oid.
+func findHistoryEntry(userID uint, business string, oid, seasonID int64) (model.WatchHistory, error) {
+	var entry model.WatchHistory
+	if seasonID > 0 && (business == "pgc" || business == "cheese") {
+		err := database.DB.Where("user_id = ? AND business = ? AND season_id = ?", userID, business, seasonID).
+			Order("view_at DESC").First(&entry).Error
+		if err != gorm.ErrRecordNotFound {
+			return entry, err
+		}
+	}
+	err := historyByOid(userID, business, oid).First(&entry).Error
+	return entry, err
+}
+
+// saveHistory inserts entry, or refreshes view_at plus the given columns on
+// the user's existing entry for the same video or season. It returns the id
+// of the entry written.
+func saveHistory(entry *model.WatchHistory, updates map[string]interface{}) uint {
+	existing, err := findHistoryEntry(entry.UserID, entry.Business, entry.Aid, entry.SeasonID)
+	if err == gorm.ErrRecordNotFound {
+		database.DB.Create(entry)
+		return entry.ID
+	}
+
+	if entry.Aid != 0 && entry.Aid != existing.Aid {
+		// Moved on to another episode of the same season: point the entry at
+		// it and fold in any older standalone row for that episode.
+		var stale []model.WatchHistory
+		historyByOid(entry.UserID, entry.Business, entry.Aid).Where("id <> ?", existing.ID).Find(&stale)
+		for _, h := range stale {
+			database.DB.Model(&model.WatchHistoryPart{}).Where("history_id = ?", h.ID).
+				Update("history_id", existing.ID)
+			database.DB.Delete(&h)
+		}
+		updates["aid"] = entry.Aid
+		updates["kid"] = entry.Kid
+		updates["bvid"] = entry.Bvid
+		updates["title"] = entry.Title
+		updates["long_title"] = entry.LongTitle
+		updates["cover"] = entry.Cover
+		updates["duration"] = entry.Duration
+	}
+
+	updates["view_at"] = entry.ViewAt
+	database.DB.Model(&existing).Updates(updates)
+	return existing.ID
+}
+
 // ---------------------------------------------------------------------------
 // POST /x/v2/history/clear  — 清空历史记录
 // ---------------------------------------------------------------------------
 
 func ClearHistory(c *gin.Context) {
 	userID := middleware.GetUserID(c)
+	database.DB.Where("user_id = ?", userID).Delete(&model.WatchHistoryPart{})
+	database.DB.Where("user_id = ?", userID).Delete(&model.PlaybackSession{})
 	database.DB.Where("user_id = ?", userID).Delete(&model.WatchHistory{})
 	response.Success(c, nil)
 }
 
+// deleteHistory removes the entries matched by query together with their
+// per-part progress rows and playback sessions, and returns how many entries
+// were removed.
+func deleteHistory(userID uint, query *gorm.DB) int {
+	var entries []model.WatchHistory
+	query.Select("id", "aid", "business").Find(&entries)
+	if len(entries) == 0 {
+		return 0
+	}
+
+	ids := make([]uint, 0, len(entries))
+	var aids []int64
+	for _, h := range entries {
+		ids = append(ids, h.ID)
+		if h.Business == "archive" || h.Business == "pgc" || h.Business == "cheese" {
+			aids = append(aids, h.Aid)
+		}
+	}
+	// Sessions are per episode, so a season entry also owns its parts' aids
+	var partAids []int64
+	database.DB.Model(&model.WatchHistoryPart{}).
+		Where("user_id = ? AND history_id IN ?", userID, ids).Pluck("aid", &partAids)
+	aids = append(aids, partAids...)
+	if len(aids) > 0 {
+		database.DB.Where("user_id = ? AND aid IN ?", userID, aids).Delete(&model.PlaybackSession{})
+	}
+
+	database.DB.Where("user_id = ? AND history_id IN ?", userID, ids).Delete(&model.WatchHistoryPart{})
+	return int(database.DB.Where("user_id = ? AND id IN ?", userID, ids).Delete(&model.WatchHistory{}).RowsAffected)
+}
+
 // ---------------------------------------------------------------------------
 // POST /x/v2/history/shadow/set  — 暂停/恢复历史记录
 // ---------------------------------------------------------------------------
@@ -239,13 +397,9 @@ func HistoryShadow(c *gin.Context) {
 func HeartBeat(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 
-	// Check if history is paused
-	var settings model.UserSettings
-	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err == nil {
-		if settings.HistoryPaused == 1 {
-			response.Success(c, nil)
-			return
-		}
+	if historyPaused(userID) {
+		response.Success(c, nil)
+		return
 	}
 
 	bvid := c.PostForm("bvid")
@@ -266,97 +420,200 @@ func HeartBeat(c *gin.Context) {
 	epid, _ := strconv.ParseInt(epidStr, 10, 64)
 	sid, _ := strconv.ParseInt(sidStr, 10, 64)
 
-	// Resolve bvid → aid early so the DB lookup uses the real aid
-	if aid == 0 && bvid != "" {
-		if info, err := bilibili.FetchVideoInfo(0, bvid); err == nil && info != nil && info.Aid != 0 {
-			aid = info.Aid
-		}
-	}
-
-	// Determine business type from type parameter
+	// Determine business type from type parameter (3=ugc, 4=pgc, 10=pugv)
 	business := "archive"
-	if typeStr == "4" || epid > 0 {
+	switch {
+	case typeStr == "10":
+		business = "cheese"
+	case typeStr == "4" || epid > 0:
 		business = "pgc"
 	}
 	_ = subTypeStr // reserved for future use
 
+	// Fetch metadata (cached after the first heartbeat). Course episodes
+	// aren't served by the archive view API, so they have their own source.
+	var info *bilibili.VideoInfo
+	var cheese *bilibili.CheeseEpisodeInfo
+	if business == "cheese" {
+		cheese, _ = bilibili.FetchCheeseEpisode(epid)
+	} else if aid != 0 || bvid != "" {
+		info, _ = bilibili.FetchVideoInfo(aid, bvid)
+		// Resolve bvid → aid so the DB lookup uses the real aid
+		if aid == 0 && info != nil {
+			aid = info.Aid
+		}
+	}
+
 	now := time.Now().Unix()
 
-	// Try to find existing record
-	var existing model.WatchHistory
-	result := database.DB.Where("user_id = ? AND aid = ?", userID, aid).First(&existing)
+	part := model.WatchHistoryPart{
+		UserID:   userID,
+		Aid:      aid,
+		Cid:      cid,
+		Epid:     epid,
+		Page:     1,
+		Progress: progress,
+		ViewAt:   now,
+	}
+	if cheese != nil {
+		part.Page = cheese.Index
+		part.Part = cheese.Title
+		part.Duration = cheese.Duration
+	} else if info != nil {
+		part.Duration = info.Duration
+		if p := info.PageByCid(cid); p != nil {
+			part.Page = p.Page
+			part.Part = p.Part
+			part.Duration = p.Duration
+		}
+	}
 
-	if result.Error == gorm.ErrRecordNotFound {
-		// New entry — fetch video metadata from Bilibili
-		info, _ := bilibili.FetchVideoInfo(aid, bvid)
-
-		title := ""
-		pic := ""
-		duration := 0
-		ownerMid := int64(0)
-		ownerName := ""
-		ownerFace := ""
-		videos := 0
-		fetchedBvid := bvid
+	entry := model.WatchHistory{
+		UserID:   userID,
+		Aid:      aid,
+		Bvid:     bvid,
+		Cid:      cid,
+		Page:     part.Page,
+		Part:     part.Part,
+		Epid:     epid,
+		SeasonID: sid,
+		Progress: progress,
+		Badge:    badgeFromBusiness(business),
+		Kid:      model.HistoryKid(business, aid),
+		Business: business,
+		ViewAt:   now,
+	}
+	if cheese != nil {
+		fillCheeseHistory(&entry, cheese)
+	} else if info != nil {
+		fillVideoHistory(&entry, info)
+	}
 
-		if info != nil {
-			title = info.Title
-			pic = info.Pic
-			duration = info.Duration
-			ownerMid = info.OwnerMid
-			ownerName = info.OwnerName
-			ownerFace = info.OwnerFace
-			videos = info.Videos
-			if info.Bvid != "" {
-				fetchedBvid = info.Bvid
-			}
-			if info.Aid != 0 {
-				aid = info.Aid
-			}
-		}
+	// Near the end (or played_time=-1) the part counts as finished
+	part.Progress, entry.IsFinish = finishedProgress(progress, part.Duration)
+	entry.Progress = part.Progress
 
-		entry := model.WatchHistory{
-			UserID:     userID,
-			Aid:        aid,
-			Bvid:       fetchedBvid,
-			Cid:        cid,
-			Epid:       epid,
-			SeasonID:   sid,
-			Title:      title,
-			Cover:      pic,
-			Duration:   duration,
-			Progress:   progress,
-			AuthorMid:  ownerMid,
-			AuthorName: ownerName,
-			AuthorFace: ownerFace,
-			Badge:      badgeFromBusiness(business),
-			Kid:        aidStr,
-			Business:   business,
-			ViewAt:     now,
-			Videos:     videos,
-		}
-		database.DB.Create(&entry)
-	} else {
-		// Update existing record
-		updates := map[string]interface{}{
-			"progress": progress,
-			"view_at":  now,
-		}
-		if cid > 0 {
-			updates["cid"] = cid
-		}
-		if epid > 0 {
-			updates["epid"] = epid
-		}
-		if sid > 0 {
-			updates["season_id"] = sid
-		}
-		database.DB.Model(&existing).Updates(updates)
+	updates := map[string]interface{}{
+		"progress":  entry.Progress,
+		"is_finish": entry.IsFinish,
+		"page":      part.Page,
+		"part":      part.Part,
+	}
+	if cid > 0 {
+		updates["cid"] = cid
+	}
+	if epid > 0 {
+		updates["epid"] = epid
 	}
+	if sid > 0 {
+		updates["season_id"] = sid
+	}
+
+	part.Aid = entry.Aid
+	part.HistoryID = saveHistory(&entry, updates)
+	saveHistoryPart(&part)
+	syncWatchLaterProgress(userID, entry.Aid, entry.Progress)
+	recordPlayback(&entry, cid, progress, part.Duration, heartbeatDevice(c), now)
 
 	response.Success(c, nil)
 }
 
+// finishMargin is how close to the end a part must be played to count as
+// finished, in seconds; parts past 95% of their length also count.
+const finishMargin = 5
+
+// progressFinished reports whether a position means the part was watched to
+// the end. A progress of -1 is the client (or a stored entry) saying so.
+func progressFinished(progress, duration int) bool {
+	if progress == -1 {
+		return true
+	}
+	return duration > 0 && progress > 0 &&
+		(progress >= duration-finishMargin || progress*100 >= duration*95)
+}
+
+// finishedProgress maps a reported position to the stored progress and
+// is_finish flag: finished parts are stored as -1.
+func finishedProgress(progress, duration int) (int, int) {
+	if progressFinished(progress, duration) {
+		return -1, 1
+	}
+	if progress < 0 {
+		progress = 0
+	}
+	return progress, 0
+}
+
+// syncWatchLaterProgress mirrors a video's progress onto the user's watch
+// later list. Finishing a video marks it viewed (or removes it, if the user
+// asked for that); rewatching it from the start keeps it viewed.
+func syncWatchLaterProgress(userID uint, aid int64, progress int) {
+	if aid == 0 {
+		return
+	}
+	if progress == -1 {
+		var settings model.UserSettings
+		database.DB.Where("user_id = ?", userID).First(&settings)
+		if settings.ToviewAutoRemove == 1 {
+			database.DB.Where("user_id = ? AND aid = ?", userID, aid).Delete(&model.WatchLater{})
+			return
+		}
+	}
+	updates := map[string]interface{}{"progress": progress}
+	if progress == -1 {
+		updates["viewed"] = 1
+	}
+	database.DB.Model(&model.WatchLater{}).Where("user_id = ? AND aid = ?", userID, aid).Updates(updates)
+}
+
+// lastPlayTime converts a stored progress to the player's resume point in
+// milliseconds. Finished parts start over from the beginning.
+func lastPlayTime(progress int) int {
+	if progress < 0 {
+		return 0
+	}
+	return progress * 1000
+}
+
+// fillVideoHistory copies archive metadata onto a history entry.
+func fillVideoHistory(entry *model.WatchHistory, info *bilibili.VideoInfo) {
+	if info.Aid != 0 {
+		entry.Aid = info.Aid
+	}
+	if info.Bvid != "" {
+		entry.Bvid = info.Bvid
+	}
+	entry.Title = info.Title
+	entry.Cover = info.Pic
+	entry.Duration = info.Duration
+	entry.AuthorMid = info.OwnerMid
+	entry.AuthorName = info.OwnerName
+	entry.AuthorFace = info.OwnerFace
+	entry.Videos = info.Videos
+}
+
+// saveHistoryPart upserts the resume point of a single part.
+func saveHistoryPart(part *model.WatchHistoryPart) {
+	if part.Aid == 0 || part.Cid == 0 {
+		return
+	}
+	var existing model.WatchHistoryPart
+	if database.DB.Where("user_id = ? AND aid = ? AND cid = ?", part.UserID, part.Aid, part.Cid).
+		First(&existing).Error == gorm.ErrRecordNotFound {
+		database.DB.Create(part)
+		return
+	}
+	database.DB.Model(&existing).Updates(map[string]interface{}{
+		"history_id": part.HistoryID,
+		"epid":       part.Epid,
+		"page":       part.Page,
+		"part":       part.Part,
+		"progress":   part.Progress,
+		"duration":   part.Duration,
+		"view_at":    part.ViewAt,
+	})
+}
+
 // ---------------------------------------------------------------------------
 // POST /x/v2/history/report  — 历史上报（添加历史记录）
 // ---------------------------------------------------------------------------
@@ -364,13 +621,9 @@ func HeartBeat(c *gin.Context) {
 func HistoryReport(c *gin.Context) {
 	userID := middleware.GetUserID(c)
 
-	// Check if history is paused
-	var settings model.UserSettings
-	if err := database.DB.Where("user_id = ?", userID).First(&settings).Error; err == nil {
-		if settings.HistoryPaused == 1 {
-			response.Success(c, nil)
-			return
-		}
+	if historyPaused(userID) {
+		response.Success(c, nil)
+		return
 	}
 
 	aidStr := c.PostForm("aid")
@@ -382,63 +635,36 @@ func HistoryReport(c *gin.Context) {
 		return
 	}
 
+	// Bangumi episodes collapse into their season's entry, same as heartbeats
+	sid, _ := strconv.ParseInt(c.PostForm("sid"), 10, 64)
+	epid, _ := strconv.ParseInt(c.PostForm("epid"), 10, 64)
 	business := "archive"
-	if typeStr == "4" {
+	if typeStr == "4" || epid > 0 {
 		business = "pgc"
 	}
 
-	now := time.Now().Unix()
-
-	// Check if already exists
-	var existing model.WatchHistory
-	result := database.DB.Where("user_id = ? AND aid = ?", userID, aid).First(&existing)
-
-	if result.Error == gorm.ErrRecordNotFound {
-		// Fetch video info
-		info, _ := bilibili.FetchVideoInfo(aid, "")
-
-		title := ""
-		pic := ""
-		duration := 0
-		ownerMid := int64(0)
-		ownerName := ""
-		ownerFace := ""
-		videos := 0
-		bvid := ""
-
-		if info != nil {
-			title = info.Title
-			pic = info.Pic
-			duration = info.Duration
-			ownerMid = info.OwnerMid
-			ownerName = info.OwnerName
-			ownerFace = info.OwnerFace
-			videos = info.Videos
-			bvid = info.Bvid
-		}
+	entry := model.WatchHistory{
+		UserID:   userID,
+		Aid:      aid,
+		Epid:     epid,
+		SeasonID: sid,
+		Badge:    badgeFromBusiness(business),
+		Kid:      model.HistoryKid(business, aid),
+		Business: business,
+		ViewAt:   time.Now().Unix(),
+	}
+	if info, _ := bilibili.FetchVideoInfo(aid, ""); info != nil {
+		fillVideoHistory(&entry, info)
+	}
 
-		entry := model.WatchHistory{
-			UserID:     userID,
-			Aid:        aid,
-			Bvid:       bvid,
-			Title:      title,
-			Cover:      pic,
-			Duration:   duration,
-			AuthorMid:  ownerMid,
-			AuthorName: ownerName,
-			AuthorFace: ownerFace,
-			Badge:      badgeFromBusiness(business),
-			Kid:        aidStr,
-			Business:   business,
-			ViewAt:     now,
-			Videos:     videos,
-		}
-		database.DB.Create(&entry)
-	} else {
-		database.DB.Model(&existing).Updates(map[string]interface{}{
-			"view_at": now,
-		})
+	updates := map[string]interface{}{}
+	if epid > 0 {
+		updates["epid"] = epid
 	}
+	if sid > 0 {
+		updates["season_id"] = sid
+	}
+	saveHistory(&entry, updates)
 
 	response.Success(c, nil)
 }
@@ -447,6 +673,8 @@ func badgeFromBusiness(business string) string {
 	switch business {
 	case "pgc":
 		return "番剧"
+	case "cheese":
+		return "课程"
 	case "live":
 		return "直播"
 	case "article":
@@ -456,6 +684,182 @@ func badgeFromBusiness(business string) string {
 	}
 }
 
+// ---------------------------------------------------------------------------
+// POST /x/v2/history/report/live  — 直播间观看记录上报
+// ---------------------------------------------------------------------------
+
+func HistoryReportLive(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	if historyPaused(userID) {
+		response.Success(c, nil)
+		return
+	}
+
+	roomID, _ := strconv.ParseInt(c.PostForm("room_id"), 10, 64)
+	if roomID == 0 {
+		response.BadRequest(c, "room_id is required")
+		return
+	}
+
+	entry := model.WatchHistory{
+		UserID:   userID,
+		Aid:      roomID,
+		Badge:    badgeFromBusiness("live"),
+		Kid:      model.HistoryKid("live", roomID),
+		Business: "live",
+		URI:      fmt.Sprintf("bilibili://live/%d", roomID),
+		ViewAt:   time.Now().Unix(),
+	}
+	updates := map[string]interface{}{}
+
+	if info, err := bilibili.FetchLiveRoomInfo(roomID); err == nil {
+		entry.Title = info.Title
+		entry.Cover = info.Cover
+		entry.AuthorMid = info.OwnerMid
+		entry.AuthorName = info.OwnerName
+		entry.AuthorFace = info.OwnerFace
+		entry.LiveStatus = info.LiveStatus
+		entry.TagName = info.AreaName
+		// Room title, cover and status change between visits
+		updates["title"] = info.Title
+		updates["cover"] = info.Cover
+		updates["author_name"] = info.OwnerName
+		updates["author_face"] = info.OwnerFace
+		updates["live_status"] = info.LiveStatus
+		updates["tag_name"] = info.AreaName
+	}
+
+	saveHistory(&entry, updates)
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v2/history/report/article  — 专栏阅读记录上报
+// ---------------------------------------------------------------------------
+
+func HistoryReportArticle(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	if historyPaused(userID) {
+		response.Success(c, nil)
+		return
+	}
+
+	cvid, _ := strconv.ParseInt(c.PostForm("id"), 10, 64)
+	if cvid == 0 {
+		response.BadRequest(c, "id is required")
+		return
+	}
+
+	entry := model.WatchHistory{
+		UserID:   userID,
+		Aid:      cvid,
+		Badge:    badgeFromBusiness("article"),
+		Kid:      model.HistoryKid("article", cvid),
+		Business: "article",
+		URI:      fmt.Sprintf("https://www.bilibili.com/read/cv%d", cvid),
+		ViewAt:   time.Now().Unix(),
+	}
+
+	if info, err := bilibili.FetchArticleInfo(cvid); err == nil {
+		entry.Title = info.Title
+		entry.Covers = strings.Join(info.Covers, ",")
+		if len(info.Covers) > 0 {
+			entry.Cover = info.Covers[0]
+		}
+		entry.AuthorMid = info.AuthorMid
+		entry.AuthorName = info.AuthorName
+	}
+
+	saveHistory(&entry, map[string]interface{}{})
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v2/history/report/cheese  — 课程观看记录上报
+// ---------------------------------------------------------------------------
+
+func HistoryReportCheese(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	if historyPaused(userID) {
+		response.Success(c, nil)
+		return
+	}
+
+	epid, _ := strconv.ParseInt(c.PostForm("ep_id"), 10, 64)
+	if epid == 0 {
+		response.BadRequest(c, "ep_id is required")
+		return
+	}
+	played, _ := strconv.Atoi(c.DefaultPostForm("played_time", "0"))
+
+	info, err := bilibili.FetchCheeseEpisode(epid)
+	if err != nil {
+		response.InternalError(c, "failed to fetch course info: "+err.Error())
+		return
+	}
+	progress, isFinish := finishedProgress(played, info.Duration)
+
+	entry := model.WatchHistory{
+		UserID:   userID,
+		Progress: progress,
+		IsFinish: isFinish,
+		Badge:    badgeFromBusiness("cheese"),
+		Business: "cheese",
+		ViewAt:   time.Now().Unix(),
+	}
+	fillCheeseHistory(&entry, info)
+	entry.Page = info.Index
+	entry.Part = info.Title
+
+	historyID := saveHistory(&entry, map[string]interface{}{
+		"progress":  progress,
+		"is_finish": isFinish,
+		"cid":       info.Cid,
+		"epid":      info.Epid,
+		"page":      info.Index,
+		"part":      info.Title,
+		"uri":       entry.URI,
+	})
+	saveHistoryPart(&model.WatchHistoryPart{
+		UserID:    userID,
+		HistoryID: historyID,
+		Aid:       info.Aid,
+		Cid:       info.Cid,
+		Epid:      info.Epid,
+		Page:      info.Index,
+		Part:      info.Title,
+		Progress:  progress,
+		Duration:  info.Duration,
+		ViewAt:    entry.ViewAt,
+	})
+	response.Success(c, nil)
+}
+
+// fillCheeseHistory copies course episode metadata onto a history entry.
+// Course entries are keyed by the episode's aid, like the official client.
+func fillCheeseHistory(entry *model.WatchHistory, info *bilibili.CheeseEpisodeInfo) {
+	entry.Aid = info.Aid
+	entry.Kid = model.HistoryKid("cheese", info.Aid)
+	entry.Cid = info.Cid
+	entry.Epid = info.Epid
+	entry.SeasonID = info.SeasonID
+	entry.Title = info.SeasonTitle
+	entry.LongTitle = info.Title
+	entry.Cover = info.Cover
+	if entry.Cover == "" {
+		entry.Cover = info.SeasonCover
+	}
+	entry.Duration = info.Duration
+	entry.AuthorMid = info.OwnerMid
+	entry.AuthorName = info.OwnerName
+	entry.AuthorFace = info.OwnerFace
+	entry.Videos = info.Total
+	entry.URI = fmt.Sprintf("https://www.bilibili.com/cheese/play/ep%d", info.Epid)
+}
+
 // ---------------------------------------------------------------------------
 // POST /x/v1/medialist/history  — 媒体列表历史上报
 // ---------------------------------------------------------------------------
@@ -473,9 +877,7 @@ func MedialistHistory(c *gin.Context) {
 	now := time.Now().Unix()
 
 	// Simply update view_at for the given aid (oid)
-	result := database.DB.Model(&model.WatchHistory{}).
-		Where("user_id = ? AND aid = ?", userID, oid).
-		Update("view_at", now)
+	result := historyByOid(userID, "archive", oid).Update("view_at", now)
 
 	if result.RowsAffected == 0 {
 		// Entry doesn't exist yet — create a minimal one
@@ -484,6 +886,7 @@ func MedialistHistory(c *gin.Context) {
 			UserID:   userID,
 			Aid:      oid,
 			Business: "archive",
+			Kid:      model.HistoryKid("archive", oid),
 			ViewAt:   now,
 		}
 		if info != nil {
@@ -511,6 +914,7 @@ func HistoryProgress(c *gin.Context) {
 
 	aidStr := c.DefaultQuery("aid", "0")
 	bvid := c.DefaultQuery("bvid", "")
+	cid, _ := strconv.ParseInt(c.DefaultQuery("cid", "0"), 10, 64)
 
 	aid, _ := strconv.ParseInt(aidStr, 10, 64)
 
@@ -529,9 +933,24 @@ func HistoryProgress(c *gin.Context) {
 		return
 	}
 
+	// Per-part resume point: the requested cid, or the last part played
+	partQuery := database.DB.Where("user_id = ? AND aid = ?", userID, aid)
+	if cid > 0 {
+		partQuery = partQuery.Where("cid = ?", cid)
+	}
+	var part model.WatchHistoryPart
+	if partQuery.Order("view_at DESC").First(&part).Error == nil {
+		response.Success(c, gin.H{
+			"last_play_time": lastPlayTime(part.Progress),
+			"last_play_cid":  part.Cid,
+		})
+		return
+	}
+
+	// Entries recorded before per-part tracking only know their last cid
 	var entry model.WatchHistory
-	result := database.DB.Where("user_id = ? AND aid = ?", userID, aid).First(&entry)
-	if result.Error != nil {
+	result := historyByOid(userID, "archive", aid).First(&entry)
+	if result.Error != nil || (cid > 0 && entry.Cid != cid) {
 		response.Success(c, gin.H{
 			"last_play_time": -1,
 			"last_play_cid":  0,
@@ -540,7 +959,7 @@ func HistoryProgress(c *gin.Context) {
 	}
 
 	response.Success(c, gin.H{
-		"last_play_time": entry.Progress * 1000, // seconds → milliseconds
+		"last_play_time": lastPlayTime(entry.Progress),
 		"last_play_cid":  entry.Cid,
 	})
 }
@@ -0,0 +1,185 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// History retention & pruning
+// ===========================================================================
+
+const (
+	pruneInterval = 1 * time.Hour
+	// Entries viewed more recently than this are never dropped for low
+	// progress — the user may still be watching.
+	pruneGrace = 1 * time.Hour
+)
+
+// StartHistoryPruner launches a goroutine that periodically enforces every
+// user's history retention settings.
+func StartHistoryPruner() {
+	go func() {
+		pruneAllHistory()
+
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			pruneAllHistory()
+		}
+	}()
+}
+
+func pruneAllHistory() {
+	var all []model.UserSettings
+	database.DB.Where("history_keep_days > 0 OR history_keep_entries > 0 OR history_min_progress > 0").
+		Find(&all)
+
+	pruned, users := 0, 0
+	for _, s := range all {
+		if n := pruneHistory(&s); n > 0 {
+			pruned += n
+			users++
+		}
+	}
+	if pruned > 0 {
+		log.Printf("[history] retention pruning removed %d entries for %d users", pruned, users)
+	}
+}
+
+// pruneHistory applies one user's retention rules and returns the number of
+// entries removed.
+func pruneHistory(s *model.UserSettings) int {
+	now := time.Now()
+	base := func() *gorm.DB {
+		return database.DB.Model(&model.WatchHistory{}).Where("user_id = ?", s.UserID)
+	}
+	removed := 0
+
+	if s.HistoryKeepDays > 0 {
+		cutoff := now.AddDate(0, 0, -s.HistoryKeepDays).Unix()
+		removed += deleteHistory(s.UserID, base().Where("view_at < ?", cutoff))
+		// Older viewings of entries that are still recent go too
+		database.DB.Where("user_id = ? AND end_at < ?", s.UserID, cutoff).Delete(&model.PlaybackSession{})
+	}
+
+	if s.HistoryMinProgress > 0 {
+		// Finished videos (progress = -1) are kept; live rooms and articles
+		// have no progress at all.
+		removed += deleteHistory(s.UserID, base().
+			Where("business IN ?", videoBusinesses).
+			Where("progress >= 0 AND progress < ?", s.HistoryMinProgress).
+			Where("view_at < ?", now.Add(-pruneGrace).Unix()))
+	}
+
+	if s.HistoryKeepEntries > 0 {
+		// The first entry past the limit marks the cutoff; it and everything
+		// older goes.
+		var cutoff model.WatchHistory
+		if base().Order("view_at DESC, id DESC").Offset(s.HistoryKeepEntries).Limit(1).
+			Take(&cutoff).Error == nil {
+			removed += deleteHistory(s.UserID, base().
+				Where("view_at < ? OR (view_at = ? AND id <= ?)", cutoff.ViewAt, cutoff.ViewAt, cutoff.ID))
+		}
+	}
+
+	return removed
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/history/retention  — 查询历史保留策略
+// ---------------------------------------------------------------------------
+
+func HistoryRetention(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var settings model.UserSettings
+	database.DB.Where("user_id = ?", userID).First(&settings)
+
+	response.Success(c, gin.H{
+		"keep_days":    settings.HistoryKeepDays,
+		"keep_entries": settings.HistoryKeepEntries,
+		"min_progress": settings.HistoryMinProgress,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v2/history/retention/set  — 设置历史保留策略
+// ---------------------------------------------------------------------------
+
+func HistoryRetentionSet(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	updates := map[string]interface{}{}
+	for form, column := range map[string]string{
+		"keep_days":    "history_keep_days",
+		"keep_entries": "history_keep_entries",
+		"min_progress": "history_min_progress",
+	} {
+		str, ok := c.GetPostForm(form)
+		if !ok {
+			continue
+		}
+		v, err := strconv.Atoi(str)
+		if err != nil || v < 0 {
+			response.BadRequest(c, "invalid "+form)
+			return
+		}
+		updates[column] = v
+	}
+
+	var settings model.UserSettings
+	result := database.DB.Where("user_id = ?", userID).First(&settings)
+	if result.Error == gorm.ErrRecordNotFound {
+		settings = model.UserSettings{UserID: userID}
+		database.DB.Create(&settings)
+	}
+	if len(updates) > 0 {
+		database.DB.Model(&settings).Updates(updates)
+	}
+
+	// Apply the new policy right away rather than waiting for the next run
+	database.DB.Where("user_id = ?", userID).First(&settings)
+	pruneHistory(&settings)
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v2/history/delete/range  — 按时间范围删除历史记录
+// ---------------------------------------------------------------------------
+
+func DelHistoryRange(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	start, _ := strconv.ParseInt(c.PostForm("start"), 10, 64)
+	end, _ := strconv.ParseInt(c.PostForm("end"), 10, 64)
+	business := c.DefaultPostForm("business", "all")
+
+	if start <= 0 || end <= 0 || start > end {
+		response.BadRequest(c, "start and end are required and start must not be after end")
+		return
+	}
+
+	query := database.DB.Model(&model.WatchHistory{}).
+		Where("user_id = ? AND view_at BETWEEN ? AND ?", userID, start, end)
+	sessions := database.DB.Where("user_id = ? AND start_at BETWEEN ? AND ?", userID, start, end)
+	if business != "" && business != "all" {
+		query = query.Where("business = ?", business)
+		sessions = sessions.Where("business = ?", business)
+	}
+	sessions.Delete(&model.PlaybackSession{})
+
+	response.Success(c, gin.H{
+		"deleted": deleteHistory(userID, query),
+	})
+}
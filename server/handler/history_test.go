@@ -0,0 +1,27 @@
+package handler
+
+import "testing"
+
+func TestParseHistoryKid(t *testing.T) {
+	tests := []struct {
+		kid      string
+		business string
+		oid      int64
+	}{
+		{"123", "archive", 123},
+		{"archive_123", "archive", 123},
+		{"pgc_456", "pgc", 456},
+		{"live_789", "live", 789},
+		{"article-list_42", "article-list", 42},
+		{"a_b_7", "a_b", 7},
+		{"archive_", "archive", 0},
+		{"archive_abc", "archive", 0},
+		{"", "archive", 0},
+	}
+	for _, tt := range tests {
+		business, oid := parseHistoryKid(tt.kid)
+		if business != tt.business || oid != tt.oid {
+			t.Errorf("parseHistoryKid(%q) = %q, %d; want %q, %d", tt.kid, business, oid, tt.business, tt.oid)
+		}
+	}
+}
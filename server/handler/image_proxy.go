@@ -0,0 +1,111 @@
+package handler
+
+import (
+	"errors"
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"piliminusb/database"
+	"piliminusb/imagecache"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Cover & avatar cache / proxy
+// ===========================================================================
+
+const (
+	imageWarmInterval = 1 * time.Hour
+	// Upper bound on images downloaded per run.
+	imageWarmBatch = 500
+	imageWarmDelay = 200 * time.Millisecond
+)
+
+// StartImageWarmer launches a goroutine that periodically downloads the
+// covers and avatars of stored items into the local image cache, so they
+// survive the upstream video being deleted.
+func StartImageWarmer() {
+	go func() {
+		warmImages()
+
+		ticker := time.NewTicker(imageWarmInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			warmImages()
+		}
+	}()
+}
+
+func warmImages() {
+	var urls []string
+	for _, src := range []struct {
+		table  interface{}
+		column string
+	}{
+		{&model.WatchLater{}, "pic"},
+		{&model.WatchLater{}, "owner_face"},
+		{&model.FavResource{}, "cover"},
+		{&model.FavFolder{}, "cover"},
+		{&model.Following{}, "face"},
+		{&model.WatchHistory{}, "cover"},
+		{&model.WatchHistory{}, "author_face"},
+	} {
+		var col []string
+		database.DB.Model(src.table).Where(src.column+" <> ''").Distinct(src.column).Pluck(src.column, &col)
+		urls = append(urls, col...)
+	}
+
+	fetched, failed := 0, 0
+	for _, u := range urls {
+		if fetched+failed >= imageWarmBatch {
+			break
+		}
+		if imagecache.Cached(u) {
+			continue
+		}
+		if err := imagecache.Warm(u); err != nil {
+			if !errors.Is(err, imagecache.ErrNotAllowed) {
+				failed++
+			}
+			continue
+		}
+		fetched++
+		time.Sleep(imageWarmDelay)
+	}
+	if fetched+failed > 0 {
+		log.Printf("[image] cached %d images, %d failed", fetched, failed)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// GET /img?sig=...&url=...  — 图片代理 (支持 @{w}w_{h}h 缩放)
+// ---------------------------------------------------------------------------
+
+func ImageProxy(c *gin.Context) {
+	rawURL := c.Query("url")
+	if rawURL == "" {
+		response.BadRequest(c, "url is required")
+		return
+	}
+	if err := imagecache.Verify(rawURL, c.Query("sig")); errors.Is(err, imagecache.ErrBadSignature) {
+		response.Error(c, 403, -403, "invalid image signature")
+		return
+	}
+
+	data, contentType, err := imagecache.Get(rawURL)
+	if errors.Is(err, imagecache.ErrNotAllowed) {
+		response.Error(c, 403, -403, "only Bilibili images can be proxied")
+		return
+	} else if err != nil {
+		response.Error(c, 502, -502, "image unavailable: "+err.Error())
+		return
+	}
+
+	// Originals are content-addressed and variants derived from them, so a
+	// URL always maps to the same bytes.
+	c.Header("Cache-Control", "public, max-age=31536000, immutable")
+	c.Data(200, contentType, data)
+}
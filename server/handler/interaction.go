@@ -0,0 +1,62 @@
+package handler
+
+import (
+	"math"
+	"sort"
+	"time"
+
+	"piliminusb/database"
+	"piliminusb/model"
+)
+
+// ===========================================================================
+// Interaction frequency ("最常访问"), computed from watch history
+// ===========================================================================
+
+const (
+	// Views older than this don't count.
+	interactionWindow = 180 * 24 * time.Hour
+	// A view loses half its weight every interactionHalfLife.
+	interactionHalfLife = 30 * 24 * time.Hour
+)
+
+// interactionScores rates how much the user watches each UP. Every video in
+// the history counts once, decayed by how long ago it was last viewed, and
+// up to double when it was watched to the end.
+func interactionScores(userID uint) map[int64]float64 {
+	now := time.Now()
+	var rows []model.WatchHistory
+	database.DB.Select("author_mid", "view_at", "progress", "duration").
+		Where("user_id = ? AND business = ? AND author_mid > 0 AND view_at >= ?",
+			userID, "archive", now.Add(-interactionWindow).Unix()).
+		Find(&rows)
+
+	scores := make(map[int64]float64)
+	for _, h := range rows {
+		age := now.Sub(time.Unix(h.ViewAt, 0))
+		weight := math.Pow(0.5, float64(age)/float64(interactionHalfLife))
+
+		completion := 0.0
+		if h.Progress < 0 {
+			completion = 1
+		} else if h.Duration > 0 {
+			completion = math.Min(float64(h.Progress)/float64(h.Duration), 1)
+		}
+		scores[h.AuthorMid] += weight * (1 + completion)
+	}
+	return scores
+}
+
+// sortByInteraction orders follows by interaction score, most watched first.
+// Ties keep their incoming order, so callers pass follows already sorted by
+// the fallback (usually m_time DESC). specialFirst keeps special follows
+// ahead of everyone else.
+func sortByInteraction(follows []model.Following, scores map[int64]float64, specialFirst bool) {
+	sort.SliceStable(follows, func(i, j int) bool {
+		a, b := &follows[i], &follows[j]
+		if specialFirst && a.IsSpecial != b.IsSpecial {
+			return a.IsSpecial > b.IsSpecial
+		}
+		return scores[a.Mid] > scores[b.Mid]
+	})
+}
@@ -0,0 +1,554 @@
+package handler
+
+import (
+	"encoding/json"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm/clause"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/notify"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// New-upload notifications: rules, channels, delivery and SSE stream
+// ===========================================================================
+
+const (
+	notifyInterval = 30 * time.Second
+	// Videos published longer ago than this are old news, e.g. when a
+	// deleted video reappears in a crawl.
+	notifyMaxAge = 48 * time.Hour
+	// A delivery is retried with doubling delays, then given up.
+	notifyMaxAttempts = 6
+	notifyRetryBase   = time.Minute
+	notifyBatch       = 100
+	streamPing        = 25 * time.Second
+)
+
+// notifyWake nudges the delivery worker when new deliveries are queued.
+var notifyWake = make(chan struct{}, 1)
+
+// notifyNewVideos matches freshly crawled uploads of an UP against the rules
+// of everyone following them, records one event per user and video, and
+// queues a delivery per channel. Called from SaveSpaceVideos.
+func notifyNewVideos(mid int64, videos []bilibili.SpaceVideo) {
+	cutoff := time.Now().Add(-notifyMaxAge).Unix()
+	fresh := videos[:0:0]
+	for _, v := range videos {
+		if v.Pubdate >= cutoff {
+			fresh = append(fresh, v)
+		}
+	}
+	if len(fresh) == 0 {
+		return
+	}
+
+	var follows []model.Following
+	database.DB.Where("mid = ? AND attribute IN ?", mid, model.RelationFollowed).Find(&follows)
+
+	queued := false
+	for i := range follows {
+		f := &follows[i]
+		var rules []model.NotifyRule
+		database.DB.Where("user_id = ? AND enabled = ?", f.UserID, true).Order("id ASC").Find(&rules)
+		if len(rules) == 0 {
+			continue
+		}
+		for _, v := range fresh {
+			if notifyUser(f, rules, v) {
+				queued = true
+			}
+		}
+	}
+	if queued {
+		select {
+		case notifyWake <- struct{}{}:
+		default:
+		}
+	}
+}
+
+// notifyUser records one upload for one follower if any rule matches, and
+// reports whether deliveries were queued.
+func notifyUser(f *model.Following, rules []model.NotifyRule, v bilibili.SpaceVideo) bool {
+	var matched *model.NotifyRule
+	channelIDs := map[uint]bool{}
+	allChannels := false
+	for i := range rules {
+		r := &rules[i]
+		if !ruleCovers(r, f) || !r.MatchTitle(v.Title) {
+			continue
+		}
+		if matched == nil {
+			matched = r
+		}
+		ids := parseIntList(r.ChannelIDs)
+		if len(ids) == 0 {
+			allChannels = true
+		}
+		for _, id := range ids {
+			channelIDs[uint(id)] = true
+		}
+	}
+	if matched == nil {
+		return false
+	}
+
+	event := model.NotifyEvent{
+		UserID:   f.UserID,
+		Aid:      v.Aid,
+		Bvid:     v.Bvid,
+		Mid:      f.Mid,
+		Uname:    f.Name,
+		Title:    v.Title,
+		Pic:      v.Pic,
+		Duration: v.Duration,
+		Pubdate:  v.Pubdate,
+		RuleID:   matched.ID,
+	}
+	// The unique (user, aid) index makes a second crawl of the same video a no-op
+	res := database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&event)
+	if res.Error != nil || res.RowsAffected == 0 {
+		return false
+	}
+	if data, err := json.Marshal(event.ToBiliJSON()); err == nil {
+		notify.Publish(f.UserID, notify.Event{ID: event.ID, Data: data})
+	}
+
+	var channels []model.NotifyChannel
+	query := database.DB.Where("user_id = ? AND enabled = ?", f.UserID, true)
+	if !allChannels {
+		ids := make([]uint, 0, len(channelIDs))
+		for id := range channelIDs {
+			ids = append(ids, id)
+		}
+		query = query.Where("id IN ?", ids)
+	}
+	query.Find(&channels)
+	if len(channels) == 0 {
+		return false
+	}
+
+	now := time.Now().Unix()
+	deliveries := make([]model.NotifyDelivery, 0, len(channels))
+	for _, ch := range channels {
+		deliveries = append(deliveries, model.NotifyDelivery{
+			UserID:        f.UserID,
+			EventID:       event.ID,
+			ChannelID:     ch.ID,
+			Status:        model.DeliveryPending,
+			NextAttemptAt: now,
+		})
+	}
+	database.DB.Clauses(clause.OnConflict{DoNothing: true}).Create(&deliveries)
+	return true
+}
+
+// ruleCovers reports whether a rule's scope includes the followed UP.
+func ruleCovers(r *model.NotifyRule, f *model.Following) bool {
+	switch r.Scope {
+	case model.NotifyScopeSpecial:
+		return f.IsSpecial == 1 && f.Attribute == model.RelationFollow
+	case model.NotifyScopeTag:
+		var n int64
+		database.DB.Model(&model.FollowTagMember{}).
+			Where("user_id = ? AND tag_id = ? AND follow_mid = ?", f.UserID, r.TagID, f.Mid).Count(&n)
+		return n > 0
+	default:
+		return true
+	}
+}
+
+// StartNotifier launches the goroutine that sends queued deliveries and
+// retries failed ones.
+func StartNotifier() {
+	go func() {
+		ticker := time.NewTicker(notifyInterval)
+		defer ticker.Stop()
+		for {
+			deliverPending()
+			select {
+			case <-ticker.C:
+			case <-notifyWake:
+			}
+		}
+	}()
+}
+
+func deliverPending() {
+	var pending []model.NotifyDelivery
+	database.DB.Where("status = ? AND next_attempt_at <= ?", model.DeliveryPending, time.Now().Unix()).
+		Order("id ASC").Limit(notifyBatch).Find(&pending)
+
+	for i := range pending {
+		d := &pending[i]
+		var ch model.NotifyChannel
+		var event model.NotifyEvent
+		if database.DB.First(&ch, d.ChannelID).Error != nil || !ch.Enabled ||
+			database.DB.First(&event, d.EventID).Error != nil {
+			database.DB.Model(d).Updates(map[string]interface{}{
+				"status":     model.DeliveryFailed,
+				"last_error": "channel or event removed",
+			})
+			continue
+		}
+
+		err := notify.Send(ch.Kind, notify.Target{URL: ch.URL, Secret: ch.Secret}, eventMessage(&event, d.ID))
+		now := time.Now()
+		if err == nil {
+			database.DB.Model(d).Updates(map[string]interface{}{
+				"status":     model.DeliverySent,
+				"attempts":   d.Attempts + 1,
+				"sent_at":    now.Unix(),
+				"last_error": "",
+			})
+			continue
+		}
+
+		attempts := d.Attempts + 1
+		updates := map[string]interface{}{
+			"attempts":        attempts,
+			"last_error":      truncate(err.Error(), 500),
+			"next_attempt_at": now.Add(notifyRetryBase << (attempts - 1)).Unix(),
+		}
+		if attempts >= notifyMaxAttempts {
+			updates["status"] = model.DeliveryFailed
+			log.Printf("[notify] delivery %d to channel %d failed: %v", d.ID, ch.ID, err)
+		}
+		database.DB.Model(d).Updates(updates)
+	}
+}
+
+// eventMessage renders an event for push channels.
+func eventMessage(e *model.NotifyEvent, deliveryID uint) *notify.Message {
+	payload := e.ToBiliJSON()
+	payload["delivery_id"] = deliveryID
+	return &notify.Message{
+		ID:      deliveryID,
+		Title:   e.Uname + " 发布了新视频",
+		Body:    fmt.Sprintf("%s\n时长 %s", e.Title, formatDuration(e.Duration)),
+		URL:     "https://www.bilibili.com/video/" + e.Bvid,
+		Image:   model.ImageURL(e.Pic),
+		Payload: payload,
+	}
+}
+
+func truncate(s string, n int) string {
+	if len(s) <= n {
+		return s
+	}
+	// Don't leave half a UTF-8 sequence behind
+	return strings.ToValidUTF8(s[:n], "")
+}
+
+// ---------------------------------------------------------------------------
+// GET  /x/notify/channels     — 推送渠道列表
+// POST /x/notify/channel/add  — 添加渠道 (kind, name, url, secret)
+// POST /x/notify/channel/del  — 删除渠道 (id)
+// POST /x/notify/channel/test — 发送测试通知 (id)
+// ---------------------------------------------------------------------------
+
+func NotifyChannels(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var channels []model.NotifyChannel
+	database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&channels)
+
+	response.Success(c, gin.H{"list": channels})
+}
+
+func NotifyChannelAdd(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	kind := c.PostForm("kind")
+	url := strings.TrimSpace(c.PostForm("url"))
+	if !notify.Supported(kind) {
+		response.BadRequest(c, "kind must be webhook, ntfy or gotify")
+		return
+	}
+	if !strings.HasPrefix(url, "http://") && !strings.HasPrefix(url, "https://") {
+		response.BadRequest(c, "url must be http(s)")
+		return
+	}
+	if err := notify.CheckURL(url); err != nil {
+		response.BadRequest(c, "url must point to a public host")
+		return
+	}
+
+	ch := model.NotifyChannel{
+		UserID:  userID,
+		Kind:    kind,
+		Name:    c.PostForm("name"),
+		URL:     url,
+		Secret:  c.PostForm("secret"),
+		Enabled: true,
+	}
+	if err := database.DB.Create(&ch).Error; err != nil {
+		response.InternalError(c, "failed to add channel")
+		return
+	}
+
+	response.Success(c, ch)
+}
+
+func NotifyChannelDel(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	id, _ := strconv.ParseUint(c.PostForm("id"), 10, 64)
+	if id == 0 {
+		response.BadRequest(c, "id is required")
+		return
+	}
+
+	database.DB.Where("user_id = ? AND channel_id = ? AND status = ?", userID, id, model.DeliveryPending).
+		Delete(&model.NotifyDelivery{})
+	database.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&model.NotifyChannel{})
+
+	response.Success(c, nil)
+}
+
+func NotifyChannelTest(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	id, _ := strconv.ParseUint(c.PostForm("id"), 10, 64)
+	var ch model.NotifyChannel
+	if err := database.DB.Where("user_id = ? AND id = ?", userID, id).First(&ch).Error; err != nil {
+		response.Error(c, 404, -404, "channel not found")
+		return
+	}
+
+	event := model.NotifyEvent{Title: "测试通知", Uname: "PiliMinusB", CreatedAt: time.Now()}
+	if err := notify.Send(ch.Kind, notify.Target{URL: ch.URL, Secret: ch.Secret}, eventMessage(&event, 0)); err != nil {
+		response.Error(c, 502, -502, err.Error())
+		return
+	}
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// GET  /x/notify/rules     — 通知规则列表
+// POST /x/notify/rule/add  — 添加规则 (scope, tagid, keywords, exclude_keywords, channel_ids)
+// POST /x/notify/rule/del  — 删除规则 (id)
+// ---------------------------------------------------------------------------
+
+func NotifyRules(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var rules []model.NotifyRule
+	database.DB.Where("user_id = ?", userID).Order("id ASC").Find(&rules)
+
+	response.Success(c, gin.H{"list": rules})
+}
+
+func NotifyRuleAdd(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	scope := c.DefaultPostForm("scope", model.NotifyScopeAll)
+	tagID, _ := strconv.ParseInt(c.PostForm("tagid"), 10, 64)
+	switch scope {
+	case model.NotifyScopeAll, model.NotifyScopeSpecial:
+		tagID = 0
+	case model.NotifyScopeTag:
+		if len(userTagIDs(database.DB, userID, []int64{tagID})) == 0 {
+			response.BadRequest(c, "tagid is not one of your follow tags")
+			return
+		}
+	default:
+		response.BadRequest(c, "scope must be all, special or tag")
+		return
+	}
+
+	channelIDs := parseIntList(c.PostForm("channel_ids"))
+	if len(channelIDs) > 0 {
+		var owned int64
+		database.DB.Model(&model.NotifyChannel{}).
+			Where("user_id = ? AND id IN ?", userID, channelIDs).Count(&owned)
+		if owned != int64(len(channelIDs)) {
+			response.BadRequest(c, "unknown channel in channel_ids")
+			return
+		}
+	}
+	ids := make([]string, 0, len(channelIDs))
+	for _, id := range channelIDs {
+		ids = append(ids, strconv.FormatInt(id, 10))
+	}
+
+	rule := model.NotifyRule{
+		UserID:          userID,
+		Scope:           scope,
+		TagID:           tagID,
+		Keywords:        c.PostForm("keywords"),
+		ExcludeKeywords: c.PostForm("exclude_keywords"),
+		ChannelIDs:      strings.Join(ids, ","),
+		Enabled:         true,
+	}
+	if err := database.DB.Create(&rule).Error; err != nil {
+		response.InternalError(c, "failed to add rule")
+		return
+	}
+
+	response.Success(c, rule)
+}
+
+func NotifyRuleDel(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	id, _ := strconv.ParseUint(c.PostForm("id"), 10, 64)
+	if id == 0 {
+		response.BadRequest(c, "id is required")
+		return
+	}
+	database.DB.Where("user_id = ? AND id = ?", userID, id).Delete(&model.NotifyRule{})
+
+	response.Success(c, nil)
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/notify/events  — 通知记录及投递状态
+// ---------------------------------------------------------------------------
+
+func NotifyEvents(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 100 {
+		ps = 20
+	}
+
+	var total int64
+	database.DB.Model(&model.NotifyEvent{}).Where("user_id = ?", userID).Count(&total)
+
+	var events []model.NotifyEvent
+	database.DB.Where("user_id = ?", userID).Order("id DESC").
+		Offset((pn - 1) * ps).Limit(ps).Find(&events)
+
+	eventIDs := make([]uint, 0, len(events))
+	for _, e := range events {
+		eventIDs = append(eventIDs, e.ID)
+	}
+	byEvent := map[uint][]model.NotifyDelivery{}
+	if len(eventIDs) > 0 {
+		var deliveries []model.NotifyDelivery
+		database.DB.Where("event_id IN ?", eventIDs).Find(&deliveries)
+		for _, d := range deliveries {
+			byEvent[d.EventID] = append(byEvent[d.EventID], d)
+		}
+	}
+
+	list := make([]map[string]interface{}, 0, len(events))
+	for i := range events {
+		item := events[i].ToBiliJSON()
+		deliveries := byEvent[events[i].ID]
+		if deliveries == nil {
+			deliveries = []model.NotifyDelivery{}
+		}
+		item["deliveries"] = deliveries
+		list = append(list, item)
+	}
+
+	response.Success(c, gin.H{
+		"list":     list,
+		"page":     gin.H{"pn": pn, "ps": ps, "total": total},
+		"has_more": int64(pn*ps) < total,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/notify/stream  — SSE 实时通知
+// ---------------------------------------------------------------------------
+
+// NotifyStream keeps a server-sent events connection open and pushes new
+// events as they are recorded. Events missed while disconnected are replayed
+// from Last-Event-ID (or ?last_event_id), and so are events dropped while
+// the connection fell behind.
+func NotifyStream(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	lastID := c.GetHeader("Last-Event-ID")
+	if lastID == "" {
+		lastID = c.Query("last_event_id")
+	}
+	sent, _ := strconv.ParseUint(lastID, 10, 64)
+	if sent == 0 {
+		// New connections start from now; later catch-ups replay from here
+		database.DB.Model(&model.NotifyEvent{}).Where("user_id = ?", userID).
+			Select("COALESCE(MAX(id), 0)").Scan(&sent)
+	}
+
+	// Subscribe before replaying so nothing recorded in between is lost
+	stream, cancel := notify.Subscribe(userID)
+	defer cancel()
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no")
+	c.Status(200)
+
+	write := func(id uint, data []byte) {
+		fmt.Fprintf(c.Writer, "id: %d\nevent: new_video\ndata: %s\n\n", id, data)
+		sent = uint64(id)
+	}
+
+	// replay writes every stored event after the last one sent
+	replay := func() {
+		for {
+			var missed []model.NotifyEvent
+			database.DB.Where("user_id = ? AND id > ?", userID, sent).
+				Order("id ASC").Limit(notifyBatch).Find(&missed)
+			for i := range missed {
+				if data, err := json.Marshal(missed[i].ToBiliJSON()); err == nil {
+					write(missed[i].ID, data)
+				}
+				sent = uint64(missed[i].ID)
+			}
+			if len(missed) < notifyBatch {
+				return
+			}
+		}
+	}
+
+	replay()
+	fmt.Fprint(c.Writer, ": connected\n\n")
+	c.Writer.Flush()
+
+	ping := time.NewTicker(streamPing)
+	defer ping.Stop()
+	for {
+		select {
+		case <-c.Request.Context().Done():
+			return
+		case <-stream.Lagged:
+			replay()
+		case e := <-stream.Events:
+			// A drop may have happened before e was queued; fill the gap first
+			select {
+			case <-stream.Lagged:
+				replay()
+			default:
+			}
+			if uint64(e.ID) <= sent {
+				continue
+			}
+			write(e.ID, e.Data)
+		case <-ping.C:
+			fmt.Fprint(c.Writer, ": ping\n\n")
+		}
+		c.Writer.Flush()
+	}
+}
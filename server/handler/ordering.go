@@ -0,0 +1,109 @@
+package handler
+
+import "strings"
+
+// ===========================================================================
+// Multi-column ordering, keyset cursors and manual reordering
+// ===========================================================================
+
+// sortKey is one column of a multi-column ordering. The last key should be
+// unique (normally id) so cursors never skip rows that share a sort value.
+type sortKey struct {
+	Column string
+	Desc   bool
+}
+
+// orderClause builds the ORDER BY clause for keys; reverse flips every
+// direction.
+func orderClause(keys []sortKey, reverse bool) string {
+	parts := make([]string, len(keys))
+	for i, k := range keys {
+		if k.Desc != reverse {
+			parts[i] = k.Column + " DESC"
+		} else {
+			parts[i] = k.Column + " ASC"
+		}
+	}
+	return strings.Join(parts, ", ")
+}
+
+// keysetAfter builds the condition selecting the rows that come after the
+// row with the given key values, in the order orderClause(keys, reverse)
+// describes.
+func keysetAfter(keys []sortKey, values []interface{}, reverse bool) (string, []interface{}) {
+	var ors []string
+	var args []interface{}
+	for i, k := range keys {
+		var ands []string
+		for j, prev := range keys[:i] {
+			ands = append(ands, prev.Column+" = ?")
+			args = append(args, values[j])
+		}
+		if k.Desc != reverse {
+			ands = append(ands, k.Column+" < ?")
+		} else {
+			ands = append(ands, k.Column+" > ?")
+		}
+		args = append(args, values[i])
+		ors = append(ors, "("+strings.Join(ands, " AND ")+")")
+	}
+	return strings.Join(ors, " OR "), args
+}
+
+// reorderSlots rearranges the listed ids within the positions they already
+// occupy in current, so reordering one page leaves the rest alone. Listed
+// ids that aren't in current are ignored.
+func reorderSlots(current, listed []int64) []int64 {
+	inCurrent := make(map[int64]bool, len(current))
+	for _, id := range current {
+		inCurrent[id] = true
+	}
+	seen := make(map[int64]bool, len(listed))
+	valid := make([]int64, 0, len(listed))
+	for _, id := range listed {
+		if inCurrent[id] && !seen[id] {
+			seen[id] = true
+			valid = append(valid, id)
+		}
+	}
+
+	out := make([]int64, len(current))
+	next := 0
+	for i, id := range current {
+		if seen[id] {
+			out[i] = valid[next]
+			next++
+		} else {
+			out[i] = id
+		}
+	}
+	return out
+}
+
+// moveAfter moves id to just after the given id in order, or to the front
+// when after is 0.
+func moveAfter(order []int64, id, after int64) []int64 {
+	out := make([]int64, 0, len(order))
+	if after == 0 {
+		out = append(out, id)
+	}
+	for _, v := range order {
+		if v == id {
+			continue
+		}
+		out = append(out, v)
+		if v == after {
+			out = append(out, id)
+		}
+	}
+	return out
+}
+
+func containsID(ids []int64, id int64) bool {
+	for _, v := range ids {
+		if v == id {
+			return true
+		}
+	}
+	return false
+}
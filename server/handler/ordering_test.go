@@ -0,0 +1,94 @@
+package handler
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestOrderClause(t *testing.T) {
+	keys := []sortKey{{"sort_order", true}, {"added_at", false}, {"id", true}}
+	tests := []struct {
+		reverse bool
+		want    string
+	}{
+		{false, "sort_order DESC, added_at ASC, id DESC"},
+		{true, "sort_order ASC, added_at DESC, id ASC"},
+	}
+	for _, tt := range tests {
+		if got := orderClause(keys, tt.reverse); got != tt.want {
+			t.Errorf("orderClause(reverse=%v) = %q, want %q", tt.reverse, got, tt.want)
+		}
+	}
+}
+
+func TestKeysetAfter(t *testing.T) {
+	tests := []struct {
+		name     string
+		keys     []sortKey
+		values   []interface{}
+		reverse  bool
+		wantCond string
+		wantArgs []interface{}
+	}{
+		{
+			name:     "single key desc",
+			keys:     []sortKey{{"id", true}},
+			values:   []interface{}{7},
+			wantCond: "(id < ?)",
+			wantArgs: []interface{}{7},
+		},
+		{
+			name:     "single key reversed",
+			keys:     []sortKey{{"id", true}},
+			values:   []interface{}{7},
+			reverse:  true,
+			wantCond: "(id > ?)",
+			wantArgs: []interface{}{7},
+		},
+		{
+			name:     "mixed directions",
+			keys:     []sortKey{{"play", true}, {"pubdate", false}, {"id", true}},
+			values:   []interface{}{100, 50, 3},
+			wantCond: "(play < ?) OR (play = ? AND pubdate > ?) OR (play = ? AND pubdate = ? AND id < ?)",
+			wantArgs: []interface{}{100, 100, 50, 100, 50, 3},
+		},
+	}
+	for _, tt := range tests {
+		cond, args := keysetAfter(tt.keys, tt.values, tt.reverse)
+		if cond != tt.wantCond || !reflect.DeepEqual(args, tt.wantArgs) {
+			t.Errorf("%s: keysetAfter = %q %v, want %q %v", tt.name, cond, args, tt.wantCond, tt.wantArgs)
+		}
+	}
+}
+
+func TestReorderSlots(t *testing.T) {
+	tests := []struct {
+		current, listed, want []int64
+	}{
+		{[]int64{1, 2, 3, 4}, []int64{3, 2}, []int64{1, 3, 2, 4}},
+		{[]int64{1, 2, 3, 4}, []int64{4, 9, 1, 4}, []int64{4, 2, 3, 1}},
+		{[]int64{1, 2, 3}, nil, []int64{1, 2, 3}},
+	}
+	for _, tt := range tests {
+		if got := reorderSlots(tt.current, tt.listed); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("reorderSlots(%v, %v) = %v, want %v", tt.current, tt.listed, got, tt.want)
+		}
+	}
+}
+
+func TestMoveAfter(t *testing.T) {
+	tests := []struct {
+		order     []int64
+		id, after int64
+		want      []int64
+	}{
+		{[]int64{1, 2, 3, 4}, 3, 0, []int64{3, 1, 2, 4}},
+		{[]int64{1, 2, 3, 4}, 1, 3, []int64{2, 3, 1, 4}},
+		{[]int64{1, 2, 3, 4}, 4, 1, []int64{1, 4, 2, 3}},
+	}
+	for _, tt := range tests {
+		if got := moveAfter(tt.order, tt.id, tt.after); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("moveAfter(%v, %d, %d) = %v, want %v", tt.order, tt.id, tt.after, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,255 @@
+package handler
+
+import (
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Playback session log (folded from heartbeats)
+// ===========================================================================
+
+const (
+	// Heartbeats further apart than this start a new session.
+	sessionGap = 10 * time.Minute
+	// At most this many seconds are credited between two heartbeats, so a
+	// player left open overnight doesn't count as hours of watching.
+	maxHeartbeatStep = 60
+)
+
+// recordPlayback folds a heartbeat into the user's current session for the
+// part, or starts a new one. progress is the reported position in seconds;
+// -1 means the part was played to the end.
+func recordPlayback(entry *model.WatchHistory, cid int64, progress, duration int, device string, now int64) {
+	if entry.Aid == 0 {
+		return
+	}
+	pos := progress
+	if pos < 0 {
+		pos = duration
+	}
+
+	var s model.PlaybackSession
+	err := database.DB.Where("user_id = ? AND aid = ? AND cid = ? AND end_at >= ?",
+		entry.UserID, entry.Aid, cid, now-int64(sessionGap.Seconds())).
+		Order("end_at DESC").First(&s).Error
+	if err == gorm.ErrRecordNotFound {
+		database.DB.Create(&model.PlaybackSession{
+			UserID:    entry.UserID,
+			Business:  entry.Business,
+			Aid:       entry.Aid,
+			Cid:       cid,
+			Epid:      entry.Epid,
+			AuthorMid: entry.AuthorMid,
+			StartAt:   now,
+			EndAt:     now,
+			StartPos:  pos,
+			EndPos:    pos,
+			Duration:  duration,
+			Ranges:    formatRanges([][2]int{{pos, pos}}),
+			Device:    device,
+		})
+		return
+	} else if err != nil {
+		return
+	}
+
+	// Credit wall-clock time since the last heartbeat, but no more than the
+	// position advanced (the player may have been paused), unless the user
+	// seeked backwards.
+	step := int(now - s.EndAt)
+	if d := pos - s.EndPos; d >= 0 && d < step {
+		step = d
+	}
+	if step > maxHeartbeatStep {
+		step = maxHeartbeatStep
+	}
+	if step < 0 {
+		step = 0
+	}
+
+	// The span since the last position was played through if the player
+	// moved forward at about real-time speed; anything else is a seek and
+	// only the new position is known to be covered.
+	ranges := parseRanges(s.Ranges)
+	if d := pos - s.EndPos; d >= 0 && d <= int(now-s.EndAt)+5 {
+		ranges = append(ranges, [2]int{s.EndPos, pos})
+	} else {
+		ranges = append(ranges, [2]int{pos, pos})
+	}
+
+	database.DB.Model(&s).Updates(map[string]interface{}{
+		"end_at":  now,
+		"end_pos": pos,
+		"watched": gorm.Expr("watched + ?", step),
+		"ranges":  formatRanges(ranges),
+	})
+}
+
+// parseRanges decodes "from-to,from-to" into position ranges.
+func parseRanges(s string) [][2]int {
+	var ranges [][2]int
+	for _, r := range strings.Split(s, ",") {
+		from, to, ok := strings.Cut(r, "-")
+		if !ok {
+			continue
+		}
+		a, err1 := strconv.Atoi(from)
+		b, err2 := strconv.Atoi(to)
+		if err1 == nil && err2 == nil {
+			ranges = append(ranges, [2]int{a, b})
+		}
+	}
+	return ranges
+}
+
+// formatRanges merges overlapping or adjacent ranges and encodes them.
+func formatRanges(ranges [][2]int) string {
+	ranges = mergeRanges(ranges)
+	parts := make([]string, len(ranges))
+	for i, r := range ranges {
+		parts[i] = fmt.Sprintf("%d-%d", r[0], r[1])
+	}
+	return strings.Join(parts, ",")
+}
+
+func mergeRanges(ranges [][2]int) [][2]int {
+	if len(ranges) == 0 {
+		return ranges
+	}
+	sort.Slice(ranges, func(i, j int) bool { return ranges[i][0] < ranges[j][0] })
+	merged := [][2]int{ranges[0]}
+	for _, r := range ranges[1:] {
+		last := &merged[len(merged)-1]
+		if r[0] <= last[1]+1 {
+			if r[1] > last[1] {
+				last[1] = r[1]
+			}
+		} else {
+			merged = append(merged, r)
+		}
+	}
+	return merged
+}
+
+// coveredSeconds is the total length of a set of merged ranges.
+func coveredSeconds(ranges [][2]int) int {
+	total := 0
+	for _, r := range ranges {
+		total += r[1] - r[0]
+	}
+	return total
+}
+
+// heartbeatDevice identifies the client that sent a heartbeat: an explicit
+// "device" field, or the User-Agent.
+func heartbeatDevice(c *gin.Context) string {
+	device := c.PostForm("device")
+	if device == "" {
+		device = c.GetHeader("User-Agent")
+	}
+	return truncate(device, 100)
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/history/sessions  — 单个视频的观看记录
+// ---------------------------------------------------------------------------
+
+func HistorySessions(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	aid, _ := strconv.ParseInt(c.Query("aid"), 10, 64)
+	bvid := c.Query("bvid")
+	cid, _ := strconv.ParseInt(c.Query("cid"), 10, 64)
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 100 {
+		ps = 20
+	}
+
+	if aid == 0 && bvid != "" {
+		var h model.WatchHistory
+		if database.DB.Where("user_id = ? AND bvid = ?", userID, bvid).First(&h).Error == nil {
+			aid = h.Aid
+		} else if info, err := bilibili.FetchVideoInfo(0, bvid); err == nil {
+			aid = info.Aid
+		}
+	}
+	if aid == 0 {
+		response.BadRequest(c, "aid or bvid required")
+		return
+	}
+
+	query := database.DB.Model(&model.PlaybackSession{}).Where("user_id = ? AND aid = ?", userID, aid)
+	if cid > 0 {
+		query = query.Where("cid = ?", cid)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var sessions []model.PlaybackSession
+	query.Order("start_at DESC").Offset((pn - 1) * ps).Limit(ps).Find(&sessions)
+
+	list := make([]gin.H, 0, len(sessions))
+	for _, s := range sessions {
+		ranges := parseRanges(s.Ranges)
+		if ranges == nil {
+			ranges = [][2]int{}
+		}
+		list = append(list, gin.H{
+			"cid":       s.Cid,
+			"epid":      s.Epid,
+			"start_at":  s.StartAt,
+			"end_at":    s.EndAt,
+			"start_pos": s.StartPos,
+			"end_pos":   s.EndPos,
+			"watched":   s.Watched,
+			"duration":  s.Duration,
+			"ranges":    ranges,
+			"covered":   coveredSeconds(ranges),
+			"device":    s.Device,
+		})
+	}
+
+	// Rewatches: sessions per part, and where the user last stopped
+	var last model.PlaybackSession
+	var lastStop gin.H
+	if database.DB.Where("user_id = ? AND aid = ?", userID, aid).
+		Order("end_at DESC").Limit(1).Find(&last).RowsAffected > 0 {
+		lastStop = gin.H{"cid": last.Cid, "end_at": last.EndAt, "end_pos": last.EndPos, "device": last.Device}
+	}
+	type partCount struct {
+		Cid   int64 `json:"cid"`
+		Count int   `json:"count"`
+	}
+	var perPart []partCount
+	database.DB.Model(&model.PlaybackSession{}).
+		Select("cid, COUNT(*) AS count").
+		Where("user_id = ? AND aid = ?", userID, aid).
+		Group("cid").Scan(&perPart)
+
+	response.Success(c, gin.H{
+		"aid":       aid,
+		"total":     total,
+		"page":      gin.H{"pn": pn, "ps": ps, "total": total},
+		"parts":     perPart,
+		"last_stop": lastStop,
+		"list":      list,
+	})
+}
@@ -0,0 +1,43 @@
+package handler
+
+import (
+	"time"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/model"
+)
+
+// ===========================================================================
+// Followed UP profiles, kept fresh by bilibili.StartProfileRefresh
+// ===========================================================================
+
+// StaleProfileMids returns the followed mids whose profile was last refreshed
+// before the given time.
+func StaleProfileMids(before time.Time) []int64 {
+	var mids []int64
+	database.DB.Model(&model.Following{}).
+		Where("attribute IN ? AND profile_at < ?", model.RelationFollowed, before.Unix()).
+		Distinct("mid").Pluck("mid", &mids)
+	return mids
+}
+
+// SaveUserProfile copies a fetched card onto every user's relation row for
+// that account. A closed account keeps its last known name and face.
+func SaveUserProfile(card *bilibili.UserCard) {
+	updates := map[string]interface{}{
+		"deactivated": card.Deactivated,
+		"profile_at":  time.Now().Unix(),
+	}
+	if card.Name != "" {
+		updates["name"] = card.Name
+		updates["face"] = card.Face
+		updates["sign"] = card.Sign
+		updates["official_type"] = card.OfficialType
+		updates["official_desc"] = card.OfficialDesc
+		updates["vip_type"] = card.VipType
+		updates["vip_status"] = card.VipStatus
+		updates["vip_due_date"] = card.VipDueDate
+	}
+	database.DB.Model(&model.Following{}).Where("mid = ?", card.Mid).Updates(updates)
+}
@@ -0,0 +1,195 @@
+package handler
+
+import (
+	"database/sql"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+	"piliminusb/search"
+)
+
+// ===========================================================================
+// Unified search across history, favorites and watch later
+// ===========================================================================
+
+// libraryIndexIdle is how long an unused index stays in memory.
+const libraryIndexIdle = 30 * time.Minute
+
+// libraryIndex is one user's search index together with the rows it was
+// built from. sig fingerprints those tables so edits trigger a rebuild.
+type libraryIndex struct {
+	sig      string
+	idx      *search.Index
+	history  []model.WatchHistory
+	favs     []model.FavResource
+	toview   []model.WatchLater
+	lastUsed time.Time
+}
+
+var (
+	libraryIndexes   = make(map[uint]*libraryIndex)
+	libraryIndexesMu sync.Mutex
+	libraryBuilds    singleflight.Group
+)
+
+// librarySignature fingerprints a user's searchable rows by row count and
+// latest update time per table — cheap enough to check on every search.
+func librarySignature(userID uint) string {
+	var parts []string
+	for _, m := range []interface{}{&model.WatchHistory{}, &model.FavResource{}, &model.WatchLater{}} {
+		var n int64
+		var ts sql.NullString
+		database.DB.Model(m).Where("user_id = ?", userID).
+			Select("COUNT(*), CAST(MAX(updated_at) AS CHAR)").Row().Scan(&n, &ts)
+		parts = append(parts, strconv.FormatInt(n, 10)+"@"+ts.String)
+	}
+	return strings.Join(parts, "|")
+}
+
+// userLibraryIndex returns the user's search index, rebuilding it if any of
+// the underlying rows changed since it was built. The map lock only guards
+// lookups and inserts; concurrent rebuilds for the same user share one load.
+func userLibraryIndex(userID uint) *libraryIndex {
+	sig := librarySignature(userID)
+
+	libraryIndexesMu.Lock()
+	// Drop indexes nobody searched in a while; they are rebuilt on demand
+	now := time.Now()
+	for id, li := range libraryIndexes {
+		if now.Sub(li.lastUsed) > libraryIndexIdle {
+			delete(libraryIndexes, id)
+		}
+	}
+	if li, ok := libraryIndexes[userID]; ok && li.sig == sig {
+		li.lastUsed = now
+		libraryIndexesMu.Unlock()
+		return li
+	}
+	libraryIndexesMu.Unlock()
+
+	key := strconv.FormatUint(uint64(userID), 10) + "|" + sig
+	v, _, _ := libraryBuilds.Do(key, func() (interface{}, error) {
+		li := buildLibraryIndex(userID, sig)
+		libraryIndexesMu.Lock()
+		libraryIndexes[userID] = li
+		libraryIndexesMu.Unlock()
+		return li, nil
+	})
+	return v.(*libraryIndex)
+}
+
+// buildLibraryIndex loads the user's searchable rows and indexes them.
+func buildLibraryIndex(userID uint, sig string) *libraryIndex {
+	li := &libraryIndex{sig: sig, lastUsed: time.Now()}
+	database.DB.Where("user_id = ?", userID).Find(&li.history)
+	database.DB.Where("user_id = ?", userID).Find(&li.favs)
+	database.DB.Where("user_id = ?", userID).Find(&li.toview)
+
+	docs := make([]search.Doc, 0, len(li.history)+len(li.favs)+len(li.toview))
+	for i, h := range li.history {
+		docs = append(docs, search.Doc{Source: "history", Ref: i, Time: h.ViewAt, Fields: []search.Field{
+			{Name: "title", Text: h.Title, Weight: 10, Pinyin: true},
+			{Name: "long_title", Text: h.LongTitle, Weight: 6, Pinyin: true},
+			{Name: "upper", Text: h.AuthorName, Weight: 5, Pinyin: true},
+			{Name: "bvid", Text: h.Bvid, Weight: 8},
+		}})
+	}
+	for i, f := range li.favs {
+		docs = append(docs, search.Doc{Source: "fav", Ref: i, Time: f.FavTime, Fields: []search.Field{
+			{Name: "title", Text: f.Title, Weight: 10, Pinyin: true},
+			{Name: "upper", Text: f.UpperName, Weight: 5, Pinyin: true},
+			{Name: "intro", Text: f.Intro, Weight: 2},
+			{Name: "bvid", Text: f.Bvid, Weight: 8},
+		}})
+	}
+	for i, w := range li.toview {
+		docs = append(docs, search.Doc{Source: "toview", Ref: i, Time: w.AddedAt, Fields: []search.Field{
+			{Name: "title", Text: w.Title, Weight: 10, Pinyin: true},
+			{Name: "upper", Text: w.OwnerName, Weight: 5, Pinyin: true},
+			{Name: "bvid", Text: w.Bvid, Weight: 8},
+		}})
+	}
+	li.idx = search.New(docs)
+	return li
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/library/search  — 跨历史/收藏/稍后再看搜索
+// ---------------------------------------------------------------------------
+
+func LibrarySearch(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	keyword := strings.TrimSpace(c.Query("keyword"))
+	source := c.DefaultQuery("source", "all") // all/history/fav/toview
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 100 {
+		ps = 20
+	}
+
+	if keyword == "" {
+		response.BadRequest(c, "keyword is required")
+		return
+	}
+
+	var sources []string
+	if source != "" && source != "all" {
+		sources = strings.Split(source, ",")
+	}
+
+	li := userLibraryIndex(userID)
+	hits := li.idx.Search(keyword, sources...)
+
+	total := len(hits)
+	offset := (pn - 1) * ps
+	if offset > total {
+		offset = total
+	}
+	end := offset + ps
+	if end > total {
+		end = total
+	}
+
+	list := make([]gin.H, 0, end-offset)
+	for _, hit := range hits[offset:end] {
+		item := gin.H{
+			"source":  hit.Doc.Source,
+			"score":   hit.Score,
+			"matches": hit.Matches,
+		}
+		switch hit.Doc.Source {
+		case "history":
+			item["item"] = li.history[hit.Doc.Ref].ToBiliJSON()
+		case "fav":
+			f := li.favs[hit.Doc.Ref]
+			item["media_id"] = f.MediaID
+			item["item"] = f.ToBiliJSON()
+		case "toview":
+			item["item"] = li.toview[hit.Doc.Ref].ToBiliJSON()
+		}
+		list = append(list, item)
+	}
+
+	response.Success(c, gin.H{
+		"list":     list,
+		"has_more": end < total,
+		"page": gin.H{
+			"pn":    pn,
+			"ps":    ps,
+			"total": total,
+		},
+	})
+}
@@ -0,0 +1,278 @@
+package handler
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Offline UP space, answered from the background crawl of followed UPs
+// ===========================================================================
+
+// SaveSpaceVideos stores one crawl of an UP's newest videos, and notifies
+// followers of the ones not seen before. Passed to
+// bilibili.StartBackgroundRefresh.
+func SaveSpaceVideos(mid int64, videos []bilibili.SpaceVideo) {
+	// The first crawl of an UP is a baseline; its videos aren't new
+	var stored int64
+	database.DB.Model(&model.SpaceArchive{}).Where("mid = ?", mid).Count(&stored)
+	known := map[int64]bool{}
+	if stored > 0 {
+		aids := make([]int64, 0, len(videos))
+		for _, v := range videos {
+			aids = append(aids, v.Aid)
+		}
+		var existing []int64
+		database.DB.Model(&model.SpaceArchive{}).Where("mid = ? AND aid IN ?", mid, aids).
+			Pluck("aid", &existing)
+		for _, aid := range existing {
+			known[aid] = true
+		}
+	}
+
+	now := time.Now().Unix()
+	var fresh []bilibili.SpaceVideo
+	rows := make([]model.SpaceArchive, 0, len(videos))
+	for _, v := range videos {
+		if v.Aid == 0 {
+			continue
+		}
+		if stored > 0 && !known[v.Aid] {
+			fresh = append(fresh, v)
+		}
+		rows = append(rows, model.SpaceArchive{
+			Mid:       mid,
+			Aid:       v.Aid,
+			Bvid:      v.Bvid,
+			Title:     v.Title,
+			Pic:       v.Pic,
+			Duration:  v.Duration,
+			Pubdate:   v.Pubdate,
+			Play:      v.Play,
+			Danmaku:   v.Danmaku,
+			FetchedAt: now,
+		})
+	}
+	if len(rows) == 0 {
+		return
+	}
+	database.DB.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "mid"}, {Name: "aid"}},
+		DoUpdates: clause.AssignmentColumns([]string{"bvid", "title", "pic", "duration", "pubdate", "play", "danmaku", "fetched_at"}),
+	}).Create(&rows)
+
+	if len(fresh) > 0 {
+		notifyNewVideos(mid, fresh)
+	}
+}
+
+// spaceUp returns what we know about an UP from users following them.
+func spaceUp(mid int64) (model.Following, bool) {
+	var f model.Following
+	err := database.DB.Where("mid = ?", mid).Order("updated_at DESC").First(&f).Error
+	return f, err == nil
+}
+
+// spaceSortKeys maps the space API order values to the order of an UP's
+// stored videos. pubdate is the default; click is play count.
+func spaceSortKeys(order string) []sortKey {
+	if order == "click" {
+		return []sortKey{{"play", true}, {"aid", true}}
+	}
+	return []sortKey{{"pubdate", true}, {"aid", true}}
+}
+
+func spaceSortValue(a *model.SpaceArchive, column string) interface{} {
+	switch column {
+	case "play":
+		return a.Play
+	case "aid":
+		return a.Aid
+	default:
+		return a.Pubdate
+	}
+}
+
+// spaceQuery selects an UP's stored videos, optionally filtered by keyword.
+func spaceQuery(mid int64, keyword string) *gorm.DB {
+	query := database.DB.Model(&model.SpaceArchive{}).Where("mid = ?", mid)
+	if keyword != "" {
+		query = query.Where("title LIKE ?", "%"+keyword+"%")
+	}
+	return query
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/space/wbi/arc/search  — UP 投稿列表 (web)
+// ---------------------------------------------------------------------------
+
+func SpaceArcSearch(c *gin.Context) {
+	mid, _ := strconv.ParseInt(c.Query("mid"), 10, 64)
+	if mid == 0 {
+		response.BadRequest(c, "mid is required")
+		return
+	}
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "30"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 50 {
+		ps = 30
+	}
+
+	query := spaceQuery(mid, c.Query("keyword"))
+
+	var total int64
+	query.Count(&total)
+
+	var archives []model.SpaceArchive
+	query.Order(orderClause(spaceSortKeys(c.Query("order")), false)).
+		Offset((pn - 1) * ps).Limit(ps).Find(&archives)
+
+	up, _ := spaceUp(mid)
+	vlist := make([]map[string]interface{}, 0, len(archives))
+	for i := range archives {
+		vlist = append(vlist, archives[i].ToSearchArchiveJSON(up.Name))
+	}
+
+	response.Success(c, gin.H{
+		"list": gin.H{
+			"vlist": vlist,
+			"tlist": gin.H{},
+		},
+		"page": gin.H{
+			"pn":    pn,
+			"ps":    ps,
+			"count": total,
+		},
+		"is_risk":       false,
+		"gaia_res_type": 0,
+		"gaia_data":     nil,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/space/archive/cursor  — UP 投稿列表 (app, 游标分页)
+// ---------------------------------------------------------------------------
+
+func SpaceArchiveCursor(c *gin.Context) {
+	mid, _ := strconv.ParseInt(c.Query("vmid"), 10, 64)
+	if mid == 0 {
+		response.BadRequest(c, "vmid is required")
+		return
+	}
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if ps < 1 || ps > 50 {
+		ps = 20
+	}
+	order := c.DefaultQuery("order", "pubdate")
+	keys := spaceSortKeys(order)
+	// sort=asc lists oldest (or least played) first
+	ascending := c.Query("sort") == "asc"
+
+	var total int64
+	spaceQuery(mid, "").Count(&total)
+
+	// The aid of the last item shown is the cursor for the next page
+	query := spaceQuery(mid, "")
+	if aid, _ := strconv.ParseInt(c.Query("aid"), 10, 64); aid > 0 {
+		var cursor model.SpaceArchive
+		if database.DB.Where("mid = ? AND aid = ?", mid, aid).First(&cursor).Error == nil {
+			values := make([]interface{}, len(keys))
+			for i, k := range keys {
+				values[i] = spaceSortValue(&cursor, k.Column)
+			}
+			cond, args := keysetAfter(keys, values, ascending)
+			query = query.Where(cond, args...)
+		}
+	}
+
+	var archives []model.SpaceArchive
+	query.Order(orderClause(keys, ascending)).Limit(ps + 1).Find(&archives)
+
+	hasNext := len(archives) > ps
+	if hasNext {
+		archives = archives[:ps]
+	}
+
+	up, _ := spaceUp(mid)
+	items := make([]map[string]interface{}, 0, len(archives))
+	for i := range archives {
+		items = append(items, archives[i].ToSpaceCursorJSON(up.Name))
+	}
+
+	response.Success(c, gin.H{
+		"item":     items,
+		"count":    total,
+		"has_next": hasNext,
+		"has_prev": c.Query("aid") != "",
+		"order": []gin.H{
+			{"title": "最新发布", "value": "pubdate"},
+			{"title": "最多播放", "value": "click"},
+		},
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/web-interface/card  — UP 名片
+// ---------------------------------------------------------------------------
+
+func SpaceCard(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	mid, _ := strconv.ParseInt(c.Query("mid"), 10, 64)
+	if mid == 0 {
+		response.BadRequest(c, "mid is required")
+		return
+	}
+
+	up, ok := spaceUp(mid)
+	if !ok {
+		response.Error(c, 404, -404, "UP is not followed by anyone on this server")
+		return
+	}
+
+	var archiveCount int64
+	spaceQuery(mid, "").Count(&archiveCount)
+
+	var following int64
+	database.DB.Model(&model.Following{}).
+		Where("user_id = ? AND mid = ? AND attribute IN ?", userID, mid, model.RelationFollowed).
+		Count(&following)
+
+	response.Success(c, gin.H{
+		"card": gin.H{
+			"mid":       strconv.FormatInt(mid, 10),
+			"name":      up.Name,
+			"face":      model.ImageURL(up.Face),
+			"sign":      up.Sign,
+			"fans":      0,
+			"attention": 0,
+			"Official": gin.H{
+				"type": up.OfficialType,
+				"desc": up.OfficialDesc,
+			},
+			"vip": gin.H{
+				"type":     up.VipType,
+				"status":   up.VipStatus,
+				"due_date": up.VipDueDate,
+			},
+		},
+		"following":     following > 0,
+		"archive_count": archiveCount,
+		"article_count": 0,
+		"follower":      0,
+		"like_num":      0,
+	})
+}
@@ -0,0 +1,442 @@
+package handler
+
+import (
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Watching statistics (aggregated from PlaybackSession)
+// ===========================================================================
+
+// statsMaxRange bounds the start/end window of a stats query; the heatmap
+// has one bucket per day of it.
+const statsMaxRange = 366 * 24 * time.Hour
+
+type statBucket struct {
+	WatchTime int `json:"watch_time"`
+	Sessions  int `json:"sessions"`
+	Videos    int `json:"videos"`
+	aids      map[int64]bool
+}
+
+func (b *statBucket) add(s *model.PlaybackSession) {
+	if b.aids == nil {
+		b.aids = map[int64]bool{}
+	}
+	b.WatchTime += s.Watched
+	b.Sessions++
+	if !b.aids[s.Aid] {
+		b.aids[s.Aid] = true
+		b.Videos++
+	}
+}
+
+// merge folds o into b, counting a video watched in both only once.
+func (b *statBucket) merge(o *statBucket) {
+	if b.aids == nil {
+		b.aids = map[int64]bool{}
+	}
+	b.WatchTime += o.WatchTime
+	b.Sessions += o.Sessions
+	for aid := range o.aids {
+		if !b.aids[aid] {
+			b.aids[aid] = true
+			b.Videos++
+		}
+	}
+}
+
+// sessionStats accumulates everything the stats endpoints report.
+type sessionStats struct {
+	total      statBucket
+	byBusiness map[string]*statBucket
+	byUp       map[int64]*statBucket
+	byVideo    map[int64]*statBucket
+	byDay      map[string]*statBucket
+	byMonth    [12]statBucket
+	byHour     [24]statBucket
+}
+
+func aggregateSessions(sessions []model.PlaybackSession) *sessionStats {
+	st := &sessionStats{
+		byBusiness: map[string]*statBucket{},
+		byUp:       map[int64]*statBucket{},
+		byVideo:    map[int64]*statBucket{},
+		byDay:      map[string]*statBucket{},
+	}
+	bucket := func(m map[string]*statBucket, k string) *statBucket {
+		if m[k] == nil {
+			m[k] = &statBucket{}
+		}
+		return m[k]
+	}
+	for i := range sessions {
+		s := &sessions[i]
+		t := time.Unix(s.StartAt, 0)
+		st.total.add(s)
+		bucket(st.byBusiness, s.Business).add(s)
+		bucket(st.byDay, t.Format("2006-01-02")).add(s)
+		st.byMonth[t.Month()-1].add(s)
+		st.byHour[t.Hour()].add(s)
+		if st.byUp[s.AuthorMid] == nil {
+			st.byUp[s.AuthorMid] = &statBucket{}
+		}
+		st.byUp[s.AuthorMid].add(s)
+		if st.byVideo[s.Aid] == nil {
+			st.byVideo[s.Aid] = &statBucket{}
+		}
+		st.byVideo[s.Aid].add(s)
+	}
+	return st
+}
+
+// heatmap returns one bucket per day (or per week, keyed by its Monday)
+// between start and end, including empty ones.
+func (st *sessionStats) heatmap(start, end time.Time, weekly bool) []gin.H {
+	day := time.Date(start.Year(), start.Month(), start.Day(), 0, 0, 0, 0, time.Local)
+	if weekly {
+		day = day.AddDate(0, 0, -((int(day.Weekday()) + 6) % 7))
+	}
+
+	var list []gin.H
+	for !day.After(end) {
+		var b statBucket
+		span := 1
+		if weekly {
+			span = 7
+		}
+		for i := 0; i < span; i++ {
+			if d := st.byDay[day.AddDate(0, 0, i).Format("2006-01-02")]; d != nil {
+				b.merge(d)
+			}
+		}
+		list = append(list, gin.H{
+			"date":       day.Format("2006-01-02"),
+			"watch_time": b.WatchTime,
+			"sessions":   b.Sessions,
+			"videos":     b.Videos,
+		})
+		day = day.AddDate(0, 0, span)
+	}
+	return list
+}
+
+// topUps returns the n UPs with the most watch time, with their name and
+// face taken from the user's history.
+func (st *sessionStats) topUps(userID uint, n int) []gin.H {
+	mids := make([]int64, 0, len(st.byUp))
+	for mid := range st.byUp {
+		if mid != 0 {
+			mids = append(mids, mid)
+		}
+	}
+	sort.Slice(mids, func(i, j int) bool {
+		return st.byUp[mids[i]].WatchTime > st.byUp[mids[j]].WatchTime
+	})
+	if len(mids) > n {
+		mids = mids[:n]
+	}
+
+	profiles := map[int64]model.WatchHistory{}
+	if len(mids) > 0 {
+		var rows []model.WatchHistory
+		database.DB.Where("user_id = ? AND author_mid IN ?", userID, mids).
+			Order("view_at ASC").Find(&rows)
+		for _, h := range rows {
+			profiles[h.AuthorMid] = h // latest wins
+		}
+	}
+
+	list := make([]gin.H, 0, len(mids))
+	for _, mid := range mids {
+		b := st.byUp[mid]
+		p := profiles[mid]
+		list = append(list, gin.H{
+			"mid":        mid,
+			"name":       p.AuthorName,
+			"face":       p.AuthorFace,
+			"watch_time": b.WatchTime,
+			"sessions":   b.Sessions,
+			"videos":     b.Videos,
+		})
+	}
+	return list
+}
+
+// topVideos returns the n videos with the most watch time.
+func (st *sessionStats) topVideos(userID uint, n int) []gin.H {
+	aids := make([]int64, 0, len(st.byVideo))
+	for aid := range st.byVideo {
+		aids = append(aids, aid)
+	}
+	sort.Slice(aids, func(i, j int) bool {
+		return st.byVideo[aids[i]].WatchTime > st.byVideo[aids[j]].WatchTime
+	})
+	if len(aids) > n {
+		aids = aids[:n]
+	}
+
+	entries := historyForAids(userID, aids)
+	list := make([]gin.H, 0, len(aids))
+	for _, aid := range aids {
+		b := st.byVideo[aid]
+		item := gin.H{
+			"aid":        aid,
+			"watch_time": b.WatchTime,
+			"sessions":   b.Sessions,
+		}
+		if h, ok := entries[aid]; ok {
+			item["title"] = h.Title
+			item["cover"] = h.Cover
+			item["bvid"] = h.Bvid
+			item["business"] = h.Business
+		}
+		list = append(list, item)
+	}
+	return list
+}
+
+func (st *sessionStats) businessBreakdown() gin.H {
+	out := gin.H{}
+	for business, b := range st.byBusiness {
+		out[business] = gin.H{
+			"watch_time": b.WatchTime,
+			"sessions":   b.Sessions,
+			"videos":     b.Videos,
+		}
+	}
+	return out
+}
+
+// historyForAids maps aids to the user's history entries, including entries
+// that hold the aid as one episode of a season.
+func historyForAids(userID uint, aids []int64) map[int64]model.WatchHistory {
+	out := map[int64]model.WatchHistory{}
+	if len(aids) == 0 {
+		return out
+	}
+
+	var entries []model.WatchHistory
+	database.DB.Where("user_id = ? AND aid IN ? AND business IN ?", userID, aids, videoBusinesses).Find(&entries)
+	for _, h := range entries {
+		out[h.Aid] = h
+	}
+
+	var parts []model.WatchHistoryPart
+	database.DB.Where("user_id = ? AND aid IN ?", userID, aids).Find(&parts)
+	byID := map[uint][]int64{}
+	for _, p := range parts {
+		if _, ok := out[p.Aid]; !ok {
+			byID[p.HistoryID] = append(byID[p.HistoryID], p.Aid)
+		}
+	}
+	if len(byID) > 0 {
+		ids := make([]uint, 0, len(byID))
+		for id := range byID {
+			ids = append(ids, id)
+		}
+		var seasons []model.WatchHistory
+		database.DB.Where("user_id = ? AND id IN ?", userID, ids).Find(&seasons)
+		for _, h := range seasons {
+			for _, aid := range byID[h.ID] {
+				out[aid] = h
+			}
+		}
+	}
+	return out
+}
+
+// historyFinished reports whether a history entry counts as watched to the end.
+func historyFinished(h *model.WatchHistory) bool {
+	return h.IsFinish == 1 || progressFinished(h.Progress, h.Duration)
+}
+
+// completionRate is the share of videos viewed in [start, end] that were
+// watched to the end.
+func completionRate(userID uint, start, end int64) (float64, int, int) {
+	var entries []model.WatchHistory
+	database.DB.Where("user_id = ? AND business IN ? AND view_at BETWEEN ? AND ?",
+		userID, videoBusinesses, start, end).Find(&entries)
+
+	total, finished := 0, 0
+	for i := range entries {
+		if entries[i].Duration <= 0 {
+			continue
+		}
+		total++
+		if historyFinished(&entries[i]) {
+			finished++
+		}
+	}
+	if total == 0 {
+		return 0, 0, 0
+	}
+	return float64(finished) / float64(total), finished, total
+}
+
+func loadSessions(userID uint, start, end int64) []model.PlaybackSession {
+	var sessions []model.PlaybackSession
+	database.DB.Where("user_id = ? AND start_at BETWEEN ? AND ?", userID, start, end).
+		Order("start_at ASC").Find(&sessions)
+	return sessions
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/history/stats  — 观看统计
+// ---------------------------------------------------------------------------
+
+func HistoryStats(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	now := time.Now()
+	end, _ := strconv.ParseInt(c.DefaultQuery("end", strconv.FormatInt(now.Unix(), 10)), 10, 64)
+	start, _ := strconv.ParseInt(c.DefaultQuery("start", strconv.FormatInt(now.AddDate(0, 0, -30).Unix(), 10)), 10, 64)
+	weekly := c.DefaultQuery("granularity", "day") == "week"
+	limit, _ := strconv.Atoi(c.DefaultQuery("limit", "10"))
+	if limit <= 0 {
+		limit = 10
+	}
+
+	if start <= 0 || end <= 0 || start > end {
+		response.BadRequest(c, "invalid start/end")
+		return
+	}
+	if end > now.Add(24*time.Hour).Unix() || end-start > int64(statsMaxRange/time.Second) {
+		response.BadRequest(c, "start/end must span at most 366 days and end within a day from now")
+		return
+	}
+
+	st := aggregateSessions(loadSessions(userID, start, end))
+	rate, finished, total := completionRate(userID, start, end)
+
+	response.Success(c, gin.H{
+		"start":            start,
+		"end":              end,
+		"total_watch_time": st.total.WatchTime,
+		"sessions":         st.total.Sessions,
+		"videos":           st.total.Videos,
+		"heatmap":          st.heatmap(time.Unix(start, 0), time.Unix(end, 0), weekly),
+		"top_ups":          st.topUps(userID, limit),
+		"completion": gin.H{
+			"rate":     rate,
+			"finished": finished,
+			"total":    total,
+		},
+		"business": st.businessBreakdown(),
+	})
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/history/stats/year  — 年度观看总结
+// ---------------------------------------------------------------------------
+
+func HistoryYearReview(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	year, _ := strconv.Atoi(c.DefaultQuery("year", strconv.Itoa(time.Now().Year())))
+	if year < 2009 || year > 9999 {
+		response.BadRequest(c, "invalid year")
+		return
+	}
+	from := time.Date(year, 1, 1, 0, 0, 0, 0, time.Local)
+	to := from.AddDate(1, 0, 0).Add(-time.Second)
+	start, end := from.Unix(), to.Unix()
+
+	sessions := loadSessions(userID, start, end)
+	st := aggregateSessions(sessions)
+	rate, finished, total := completionRate(userID, start, end)
+
+	// Sessions without a known uploader (live rooms, older rows) share mid 0
+	ups := len(st.byUp)
+	if _, ok := st.byUp[0]; ok {
+		ups--
+	}
+
+	// Active days, longest streak of consecutive days and the busiest day
+	days := make([]string, 0, len(st.byDay))
+	for d := range st.byDay {
+		days = append(days, d)
+	}
+	sort.Strings(days)
+	longest, streak := 0, 0
+	var prev time.Time
+	busiest := gin.H{}
+	busiestTime := -1
+	for _, d := range days {
+		t, _ := time.ParseInLocation("2006-01-02", d, time.Local)
+		if streak > 0 && prev.AddDate(0, 0, 1).Equal(t) {
+			streak++
+		} else {
+			streak = 1
+		}
+		prev = t
+		if streak > longest {
+			longest = streak
+		}
+		if b := st.byDay[d]; b.WatchTime > busiestTime {
+			busiestTime = b.WatchTime
+			busiest = gin.H{"date": d, "watch_time": b.WatchTime, "videos": b.Videos}
+		}
+	}
+
+	monthly := make([]gin.H, 0, 12)
+	for m, b := range st.byMonth {
+		monthly = append(monthly, gin.H{"month": m + 1, "watch_time": b.WatchTime, "videos": b.Videos})
+	}
+	hourly := make([]int, 24)
+	for h, b := range st.byHour {
+		hourly[h] = b.WatchTime
+	}
+
+	// The first video of the year and the latest-night session (00:00–05:00)
+	var first, lateNight gin.H
+	if len(sessions) > 0 {
+		entries := historyForAids(userID, []int64{sessions[0].Aid})
+		first = gin.H{"aid": sessions[0].Aid, "start_at": sessions[0].StartAt, "title": entries[sessions[0].Aid].Title}
+
+		latest := -1
+		for _, s := range sessions {
+			t := time.Unix(s.StartAt, 0)
+			if mins := t.Hour()*60 + t.Minute(); t.Hour() < 5 && mins > latest {
+				latest = mins
+				lateNight = gin.H{"aid": s.Aid, "start_at": s.StartAt}
+			}
+		}
+		if lateNight != nil {
+			aid := lateNight["aid"].(int64)
+			lateNight["title"] = historyForAids(userID, []int64{aid})[aid].Title
+		}
+	}
+
+	response.Success(c, gin.H{
+		"year":             year,
+		"total_watch_time": st.total.WatchTime,
+		"sessions":         st.total.Sessions,
+		"videos":           st.total.Videos,
+		"ups":              ups,
+		"days_active":      len(days),
+		"longest_streak":   longest,
+		"busiest_day":      busiest,
+		"monthly":          monthly,
+		"hourly":           hourly,
+		"top_ups":          st.topUps(userID, 5),
+		"top_videos":       st.topVideos(userID, 5),
+		"completion": gin.H{
+			"rate":     rate,
+			"finished": finished,
+			"total":    total,
+		},
+		"business":   st.businessBreakdown(),
+		"first":      first,
+		"late_night": lateNight,
+	})
+}
@@ -0,0 +1,178 @@
+package handler
+
+import (
+	"log"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+
+	"piliminusb/bilibili"
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Invalid (deleted) video detection & cleanup
+// ===========================================================================
+
+const (
+	validateInterval = 1 * time.Hour
+	// A stored video is re-verified at most this often.
+	validateRecheckAge = 3 * 24 * time.Hour
+	// Upper bound on upstream checks per run, to stay clear of rate limits.
+	validateBatch = 200
+	validateDelay = 2 * time.Second
+)
+
+// StartVideoValidator launches a goroutine that periodically re-checks the
+// videos stored in favorites, watch later and history against Bilibili, and
+// flags the ones that have been deleted. Favorited videos also get their play
+// count refreshed on the way.
+func StartVideoValidator() {
+	go func() {
+		validateStoredVideos()
+
+		ticker := time.NewTicker(validateInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			validateStoredVideos()
+		}
+	}()
+}
+
+func validateStoredVideos() {
+	// Never checked first, then the longest unchecked
+	var due []int64
+	database.DB.Raw(`SELECT v.aid FROM (
+			SELECT resource_id AS aid FROM fav_resources WHERE resource_type = ?
+			UNION SELECT aid FROM watch_laters
+			UNION SELECT aid FROM watch_histories WHERE business = ?
+		) AS v
+		LEFT JOIN video_checks AS c ON c.aid = v.aid
+		WHERE c.checked_at IS NULL OR c.checked_at < ?
+		ORDER BY c.checked_at ASC
+		LIMIT ?`,
+		model.FavTypeVideo, "archive", time.Now().Add(-validateRecheckAge).Unix(), validateBatch).
+		Scan(&due)
+
+	invalid := 0
+	for _, aid := range due {
+		info, err := bilibili.RecheckVideoInfo(aid)
+		time.Sleep(validateDelay)
+		if err != nil {
+			continue
+		}
+		database.DB.Save(&model.VideoCheck{Aid: aid, Invalid: info.Invalid, CheckedAt: time.Now().Unix()})
+		markVideoInvalid(aid, info.Invalid)
+		if info.Invalid {
+			invalid++
+		} else {
+			// Keeps the play count that favorite folders sort by current
+			database.DB.Model(&model.FavResource{}).
+				Where("resource_id = ? AND resource_type = ? AND play <> ?", aid, model.FavTypeVideo, info.View).
+				Update("play", info.View)
+		}
+	}
+	if len(due) > 0 {
+		log.Printf("[validate] checked %d videos, %d invalid", len(due), invalid)
+	}
+}
+
+// markVideoInvalid sets or clears the invalid flag of every stored copy of a
+// video. Videos can come back, e.g. after passing review.
+func markVideoInvalid(aid int64, invalid bool) {
+	database.DB.Model(&model.FavResource{}).
+		Where("resource_id = ? AND resource_type = ? AND invalid <> ?", aid, model.FavTypeVideo, invalid).
+		Update("invalid", invalid)
+	database.DB.Model(&model.WatchLater{}).
+		Where("aid = ? AND invalid <> ?", aid, invalid).
+		Update("invalid", invalid)
+	database.DB.Model(&model.WatchHistory{}).
+		Where("business = ? AND aid = ? AND invalid <> ?", "archive", aid, invalid).
+		Update("invalid", invalid)
+}
+
+// lostFromFav snapshots favorited resources before they are removed.
+func lostFromFav(rows []model.FavResource, now int64) []model.LostResource {
+	lost := make([]model.LostResource, 0, len(rows))
+	for _, r := range rows {
+		lost = append(lost, model.LostResource{
+			UserID:       r.UserID,
+			Source:       model.LostFromFav,
+			MediaID:      r.MediaID,
+			ResourceID:   r.ResourceID,
+			ResourceType: r.ResourceType,
+			Bvid:         r.Bvid,
+			Title:        r.Title,
+			Cover:        r.Cover,
+			Duration:     r.Duration,
+			UpperMid:     r.UpperMid,
+			UpperName:    r.UpperName,
+			Pubtime:      r.Pubtime,
+			AddedAt:      r.FavTime,
+			RemovedAt:    now,
+		})
+	}
+	return lost
+}
+
+// lostFromToview snapshots watch later videos before they are removed.
+func lostFromToview(rows []model.WatchLater, now int64) []model.LostResource {
+	lost := make([]model.LostResource, 0, len(rows))
+	for _, w := range rows {
+		lost = append(lost, model.LostResource{
+			UserID:       w.UserID,
+			Source:       model.LostFromToview,
+			ResourceID:   w.Aid,
+			ResourceType: model.FavTypeVideo,
+			Bvid:         w.Bvid,
+			Title:        w.Title,
+			Cover:        w.Pic,
+			Duration:     w.Duration,
+			UpperMid:     w.OwnerMid,
+			UpperName:    w.OwnerName,
+			Pubtime:      w.Pubdate,
+			AddedAt:      w.AddedAt,
+			RemovedAt:    now,
+		})
+	}
+	return lost
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v3/fav/resource/lost  — 已清除的失效内容
+// ---------------------------------------------------------------------------
+
+func LostResources(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	source := c.Query("source")
+	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
+	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
+	if pn < 1 {
+		pn = 1
+	}
+	if ps < 1 || ps > 100 {
+		ps = 20
+	}
+
+	query := database.DB.Model(&model.LostResource{}).Where("user_id = ?", userID)
+	if source != "" {
+		query = query.Where("source = ?", source)
+	}
+
+	var total int64
+	query.Count(&total)
+
+	var lost []model.LostResource
+	query.Order("removed_at DESC, id DESC").Offset((pn - 1) * ps).Limit(ps).Find(&lost)
+
+	response.Success(c, gin.H{
+		"list":     lost,
+		"page":     gin.H{"pn": pn, "ps": ps, "total": total},
+		"has_more": int64(pn*ps) < total,
+	})
+}
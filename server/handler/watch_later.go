@@ -21,34 +21,19 @@ func ToviewList(c *gin.Context) {
 
 	pn, _ := strconv.Atoi(c.DefaultQuery("pn", "1"))
 	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
-	viewed, _ := strconv.Atoi(c.DefaultQuery("viewed", "0"))
-	keyword := c.DefaultQuery("key", "")
 	ascStr := c.DefaultQuery("asc", "false")
 	asc := ascStr == "true" || ascStr == "1"
 
 	offset := (pn - 1) * ps
 
-	query := database.DB.Where("user_id = ?", userID)
-
-	// viewed: 0=all, 2=unwatched (progress >= 0 and not finished)
-	if viewed == 2 {
-		query = query.Where("viewed = 0")
-	}
-
-	// keyword search
-	if keyword != "" {
-		query = query.Where("title LIKE ?", "%"+keyword+"%")
-	}
+	query := toviewFilter(c, database.DB.Where("user_id = ?", userID))
 
 	// count
 	var count int64
 	query.Model(&model.WatchLater{}).Count(&count)
 
-	// order
-	order := "added_at DESC"
-	if asc {
-		order = "added_at ASC"
-	}
+	// order; id breaks ties so pages stay stable
+	order := orderClause(toviewSortKeys(c), asc)
 
 	var items []model.WatchLater
 	query.Order(order).Offset(offset).Limit(ps).Find(&items)
@@ -64,6 +49,73 @@ func ToviewList(c *gin.Context) {
 	})
 }
 
+// toviewFilter applies the viewed filter and keyword search shared by the
+// watch later list and playlist endpoints.
+//
+//	viewed: 0=all, 1=未看 (not started), 2=未看完 (unfinished), 3=已看完 (watched),
+//	        4=in progress (started, not finished; not an official value)
+func toviewFilter(c *gin.Context, query *gorm.DB) *gorm.DB {
+	viewed, _ := strconv.Atoi(c.DefaultQuery("viewed", "0"))
+	keyword := c.DefaultQuery("key", "")
+
+	switch viewed {
+	case 1:
+		query = query.Where("viewed = 0 AND progress = 0")
+	case 2:
+		query = query.Where("viewed = 0")
+	case 3:
+		query = query.Where("viewed = 1")
+	case 4:
+		query = query.Where("viewed = 0 AND progress > 0")
+	}
+
+	if keyword != "" {
+		like := "%" + keyword + "%"
+		query = query.Where("title LIKE ? OR owner_name LIKE ?", like, like)
+	}
+	return query
+}
+
+// toviewSortKeys maps sort_field to the order of the watch later list. The
+// default is the manual order (sort_order, then most recently added); the
+// official numeric values (1: added, 3: pubdate) are accepted too.
+func toviewSortKeys(c *gin.Context) []sortKey {
+	var column string
+	switch c.DefaultQuery("sort_field", "manual") {
+	case "added", "1":
+		column = "added_at"
+	case "pubdate", "3":
+		column = "pubdate"
+	case "duration":
+		column = "duration"
+	case "up":
+		column = "owner_mid"
+	default:
+		return toviewManualKeys
+	}
+	return []sortKey{{column, true}, {"id", true}}
+}
+
+var toviewManualKeys = []sortKey{{"sort_order", true}, {"added_at", true}, {"id", true}}
+
+// toviewSortValue returns the value of the sort column for a cursor item.
+func toviewSortValue(w *model.WatchLater, column string) int64 {
+	switch column {
+	case "id":
+		return int64(w.ID)
+	case "sort_order":
+		return int64(w.SortOrder)
+	case "pubdate":
+		return w.Pubdate
+	case "duration":
+		return int64(w.Duration)
+	case "owner_mid":
+		return w.OwnerMid
+	default:
+		return w.AddedAt
+	}
+}
+
 // POST /x/v2/history/toview/add
 func ToviewAdd(c *gin.Context) {
 	userID := middleware.GetUserID(c)
@@ -87,6 +139,10 @@ func ToviewAdd(c *gin.Context) {
 		response.InternalError(c, "failed to fetch video info: "+err.Error())
 		return
 	}
+	if info.Invalid {
+		response.Error(c, 404, -404, "video has been deleted")
+		return
+	}
 
 	now := time.Now().Unix()
 
@@ -106,21 +162,44 @@ func ToviewAdd(c *gin.Context) {
 		AddedAt:   now,
 	}
 
+	// Carry over progress if the video is already in the history. A finished
+	// video is being added to be watched again, so it starts over unviewed;
+	// auto-remove would otherwise drop it right away.
+	var hist model.WatchHistory
+	if historyByOid(userID, "archive", info.Aid).First(&hist).Error == nil && hist.Progress > 0 {
+		item.Progress = hist.Progress
+	}
+
+	// Added (or re-added) videos go on top of a manually arranged list
+	var maxOrder int
+	database.DB.Model(&model.WatchLater{}).Where("user_id = ?", userID).
+		Select("COALESCE(MAX(sort_order), 0)").Scan(&maxOrder)
+
 	// Upsert: if exists, update added_at
 	result := database.DB.Where("user_id = ? AND aid = ?", userID, info.Aid).First(&model.WatchLater{})
 	if result.Error == gorm.ErrRecordNotFound {
+		if maxOrder > 0 {
+			item.SortOrder = maxOrder + 1
+		}
 		database.DB.Create(&item)
 	} else {
+		updates := map[string]interface{}{
+			"added_at":   now,
+			"title":      info.Title,
+			"pic":        info.Pic,
+			"owner_name": info.OwnerName,
+			"owner_face": info.OwnerFace,
+			"progress":   item.Progress,
+			"viewed":     0,
+		}
+		if maxOrder > 0 {
+			updates["sort_order"] = maxOrder + 1
+		}
 		database.DB.Model(&model.WatchLater{}).
 			Where("user_id = ? AND aid = ?", userID, info.Aid).
-			Updates(map[string]interface{}{
-				"added_at":   now,
-				"title":      info.Title,
-				"pic":        info.Pic,
-				"owner_name": info.OwnerName,
-				"owner_face": info.OwnerFace,
-			})
+			Updates(updates)
 	}
+	pruneUserWatchLater(userID)
 
 	response.Success(c, nil)
 }
@@ -169,10 +248,14 @@ func MediaList(c *gin.Context) {
 
 // mediaListFav handles type=3 (favorites folder content).
 func mediaListFav(c *gin.Context) {
-	userID := middleware.GetUserID(c)
-
 	bizIDStr := c.DefaultQuery("biz_id", "0")
 	bizID, _ := strconv.ParseInt(bizIDStr, 10, 64)
+
+	userID, ok := favFolderOwner(c, []int64{bizID}, false)
+	if !ok {
+		response.Error(c, 403, -403, "no access to this folder")
+		return
+	}
 	ps, _ := strconv.Atoi(c.DefaultQuery("ps", "20"))
 	oidStr := c.DefaultQuery("oid", "")
 	descStr := c.DefaultQuery("desc", "true")
@@ -183,27 +266,25 @@ func mediaListFav(c *gin.Context) {
 	direction := directionStr == "true" || directionStr == "1"
 	withCurrent := withCurrentStr == "true" || withCurrentStr == "1"
 
+	// sort_field: 1=fav time (manual order), 2=play count, 3=pubdate
+	keys := favSortKeys(map[string]string{"2": "view", "3": "pubtime"}[c.DefaultQuery("sort_field", "1")])
+	ascending := desc == direction
+
 	query := database.DB.Where("user_id = ? AND media_id = ?", userID, bizID)
 
+	// Keyset cursor on the sort keys, as for watch later
 	if oidStr != "" && !withCurrent {
 		oid, err := strconv.ParseInt(oidStr, 10, 64)
 		if err == nil && oid > 0 {
 			var cursor model.FavResource
 			if err := database.DB.Where("user_id = ? AND media_id = ? AND resource_id = ?", userID, bizID, oid).
 				First(&cursor).Error; err == nil {
-				if direction {
-					if desc {
-						query = query.Where("fav_time > ?", cursor.FavTime)
-					} else {
-						query = query.Where("fav_time < ?", cursor.FavTime)
-					}
-				} else {
-					if desc {
-						query = query.Where("fav_time < ?", cursor.FavTime)
-					} else {
-						query = query.Where("fav_time > ?", cursor.FavTime)
-					}
+				values := make([]interface{}, len(keys))
+				for i, k := range keys {
+					values[i] = favSortValue(&cursor, k.Column)
 				}
+				cond, args := keysetAfter(keys, values, ascending)
+				query = query.Where(cond, args...)
 			}
 		}
 	}
@@ -212,17 +293,7 @@ func mediaListFav(c *gin.Context) {
 	database.DB.Model(&model.FavResource{}).
 		Where("user_id = ? AND media_id = ?", userID, bizID).Count(&totalCount)
 
-	order := "fav_time DESC"
-	if !desc {
-		order = "fav_time ASC"
-	}
-	if direction {
-		if desc {
-			order = "fav_time ASC"
-		} else {
-			order = "fav_time DESC"
-		}
-	}
+	order := orderClause(keys, ascending)
 
 	var items []model.FavResource
 	query.Order(order).Limit(ps + 1).Find(&items)
@@ -263,10 +334,22 @@ func mediaListWatchLater(c *gin.Context) {
 	desc := descStr == "true" || descStr == "1"
 	direction := directionStr == "true" || directionStr == "1"
 	withCurrent := withCurrentStr == "true" || withCurrentStr == "1"
+	keys := toviewSortKeys(c)
 
-	query := database.DB.Where("user_id = ?", userID)
+	query := toviewFilter(c, database.DB.Where("user_id = ?", userID))
+
+	// Count total (before the cursor narrows the query)
+	var totalCount int64
+	toviewFilter(c, database.DB.Where("user_id = ?", userID)).Model(&model.WatchLater{}).Count(&totalCount)
+
+	// Scanning towards larger sort values: load previous in desc order, or
+	// load next in asc order. direction=true (load previous) reverses the
+	// sort so we fetch items closest to the cursor, then reverses the
+	// results back before returning.
+	ascending := desc == direction
 
-	// Cursor-based pagination using oid (aid of boundary item).
+	// Cursor-based pagination using oid (aid of boundary item), keyed on
+	// (sort columns, id) so items sharing a sort value aren't skipped.
 	// When with_current=true this is the initial load — return all items
 	// from the beginning (oid just marks the current video, not a filter).
 	if oidStr != "" && !withCurrent {
@@ -274,43 +357,17 @@ func mediaListWatchLater(c *gin.Context) {
 		if err == nil && oid > 0 {
 			var cursor model.WatchLater
 			if err := database.DB.Where("user_id = ? AND aid = ?", userID, oid).First(&cursor).Error; err == nil {
-				if direction {
-					// Load previous
-					if desc {
-						query = query.Where("added_at > ?", cursor.AddedAt)
-					} else {
-						query = query.Where("added_at < ?", cursor.AddedAt)
-					}
-				} else {
-					// Load next (with_current is always false here)
-					if desc {
-						query = query.Where("added_at < ?", cursor.AddedAt)
-					} else {
-						query = query.Where("added_at > ?", cursor.AddedAt)
-					}
+				values := make([]interface{}, len(keys))
+				for i, k := range keys {
+					values[i] = toviewSortValue(&cursor, k.Column)
 				}
+				cond, args := keysetAfter(keys, values, ascending)
+				query = query.Where(cond, args...)
 			}
 		}
 	}
 
-	// Count total
-	var totalCount int64
-	database.DB.Where("user_id = ?", userID).Model(&model.WatchLater{}).Count(&totalCount)
-
-	// Order: for normal loads use the requested sort order.
-	// For direction=true (load previous), reverse the sort so we fetch items
-	// closest to the cursor, then reverse the results back before returning.
-	order := "added_at DESC"
-	if !desc {
-		order = "added_at ASC"
-	}
-	if direction {
-		if desc {
-			order = "added_at ASC"
-		} else {
-			order = "added_at DESC"
-		}
-	}
+	order := orderClause(keys, ascending)
 
 	var items []model.WatchLater
 	query.Order(order).Limit(ps + 1).Find(&items)
@@ -352,7 +409,21 @@ func ToviewClear(c *gin.Context) {
 		// Clear watched only
 		query = query.Where("viewed = 1")
 	case "1":
-		// Clear invalid — no real concept here, do nothing
+		// Clear invalid, keeping a snapshot of what they were
+		var invalid []model.WatchLater
+		database.DB.Where("user_id = ? AND invalid = ?", userID, true).Find(&invalid)
+		if len(invalid) > 0 {
+			err := database.DB.Transaction(func(tx *gorm.DB) error {
+				if err := tx.Create(lostFromToview(invalid, time.Now().Unix())).Error; err != nil {
+					return err
+				}
+				return tx.Delete(&invalid).Error
+			})
+			if err != nil {
+				response.InternalError(c, "clean failed: "+err.Error())
+				return
+			}
+		}
 		response.Success(c, nil)
 		return
 	default:
@@ -363,3 +434,82 @@ func ToviewClear(c *gin.Context) {
 
 	response.Success(c, nil)
 }
+
+// ---------------------------------------------------------------------------
+// Manual order
+// ---------------------------------------------------------------------------
+
+// toviewManualOrder returns the user's watch later aids in manual order.
+func toviewManualOrder(userID uint) []int64 {
+	var aids []int64
+	database.DB.Model(&model.WatchLater{}).Where("user_id = ?", userID).
+		Order(orderClause(toviewManualKeys, false)).Pluck("aid", &aids)
+	return aids
+}
+
+// saveToviewOrder renumbers sort_order so the list reads in the given order,
+// first item highest.
+func saveToviewOrder(userID uint, aids []int64) {
+	database.DB.Transaction(func(tx *gorm.DB) error {
+		for i, aid := range aids {
+			if err := tx.Model(&model.WatchLater{}).
+				Where("user_id = ? AND aid = ?", userID, aid).
+				Update("sort_order", len(aids)-i).Error; err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+}
+
+// POST /x/v2/history/toview/sort
+//
+// resources is a full or partial list of aids in their new order. A partial
+// list is rearranged within the slots its items already occupy, so
+// reordering one page leaves the rest of the list alone.
+func ToviewSort(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	resourcesStr := c.PostForm("sort")
+	if resourcesStr == "" {
+		resourcesStr = c.PostForm("resources")
+	}
+	aids := parseIntList(resourcesStr)
+	if len(aids) == 0 {
+		response.BadRequest(c, "resources is required")
+		return
+	}
+
+	saveToviewOrder(userID, reorderSlots(toviewManualOrder(userID), aids))
+
+	response.Success(c, nil)
+}
+
+// POST /x/v2/history/toview/pin
+//
+// Moves a video to the top of the list, or right after another video when
+// after is given ("play next").
+func ToviewPin(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	aid, _ := strconv.ParseInt(c.PostForm("aid"), 10, 64)
+	after, _ := strconv.ParseInt(c.PostForm("after"), 10, 64)
+	if aid == 0 {
+		response.BadRequest(c, "aid is required")
+		return
+	}
+
+	current := toviewManualOrder(userID)
+	if !containsID(current, aid) {
+		response.BadRequest(c, "video is not in watch later")
+		return
+	}
+	if after == aid || (after != 0 && !containsID(current, after)) {
+		response.BadRequest(c, "invalid after")
+		return
+	}
+
+	saveToviewOrder(userID, moveAfter(current, aid, after))
+
+	response.Success(c, nil)
+}
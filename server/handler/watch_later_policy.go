@@ -0,0 +1,166 @@
+package handler
+
+import (
+	"log"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
+
+	"piliminusb/database"
+	"piliminusb/middleware"
+	"piliminusb/model"
+	"piliminusb/response"
+)
+
+// ===========================================================================
+// Watch later maintenance (auto-remove watched, expiry, size cap)
+// ===========================================================================
+
+// StartWatchLaterPruner launches a goroutine that periodically enforces every
+// user's watch later maintenance settings, so videos expire without the user
+// adding anything.
+func StartWatchLaterPruner() {
+	go func() {
+		pruneAllWatchLater()
+
+		ticker := time.NewTicker(pruneInterval)
+		defer ticker.Stop()
+		for range ticker.C {
+			pruneAllWatchLater()
+		}
+	}()
+}
+
+func pruneAllWatchLater() {
+	var all []model.UserSettings
+	database.DB.Where("toview_expire_days > 0 OR toview_max_size > 0 OR toview_auto_remove > 0").
+		Find(&all)
+
+	pruned, users := 0, 0
+	for _, s := range all {
+		if n := pruneWatchLater(&s); n > 0 {
+			pruned += n
+			users++
+		}
+	}
+	if pruned > 0 {
+		log.Printf("[toview] maintenance removed %d videos for %d users", pruned, users)
+	}
+}
+
+// pruneWatchLater applies one user's watch later rules and returns the
+// number of videos removed. It runs from the watch later pruner, after every
+// add and whenever the rules change.
+func pruneWatchLater(s *model.UserSettings) int {
+	if s.ToviewAutoRemove != 1 && s.ToviewExpireDays <= 0 && s.ToviewMaxSize <= 0 {
+		return 0
+	}
+	var items []model.WatchLater
+	database.DB.Select("id", "added_at", "viewed").Where("user_id = ?", s.UserID).Find(&items)
+
+	ids := watchLaterEvictions(s, items, time.Now())
+	if len(ids) == 0 {
+		return 0
+	}
+	return int(database.DB.Where("user_id = ? AND id IN ?", s.UserID, ids).
+		Delete(&model.WatchLater{}).RowsAffected)
+}
+
+// watchLaterEvictions picks the videos the rules remove: watched ones, ones
+// added before the expiry cutoff, then the oldest beyond the size cap.
+func watchLaterEvictions(s *model.UserSettings, items []model.WatchLater, now time.Time) []uint {
+	var evict []uint
+	kept := make([]model.WatchLater, 0, len(items))
+	cutoff := now.AddDate(0, 0, -s.ToviewExpireDays).Unix()
+	for _, w := range items {
+		if (s.ToviewAutoRemove == 1 && w.Viewed == 1) || (s.ToviewExpireDays > 0 && w.AddedAt < cutoff) {
+			evict = append(evict, w.ID)
+		} else {
+			kept = append(kept, w)
+		}
+	}
+
+	if s.ToviewMaxSize > 0 && len(kept) > s.ToviewMaxSize {
+		// Evict oldest-first, keeping the N newest
+		sort.Slice(kept, func(i, j int) bool {
+			if kept[i].AddedAt != kept[j].AddedAt {
+				return kept[i].AddedAt > kept[j].AddedAt
+			}
+			return kept[i].ID > kept[j].ID
+		})
+		for _, w := range kept[s.ToviewMaxSize:] {
+			evict = append(evict, w.ID)
+		}
+	}
+	return evict
+}
+
+// pruneUserWatchLater loads a user's settings and applies their watch later
+// rules.
+func pruneUserWatchLater(userID uint) {
+	var settings model.UserSettings
+	if database.DB.Where("user_id = ?", userID).First(&settings).Error == nil {
+		pruneWatchLater(&settings)
+	}
+}
+
+// ---------------------------------------------------------------------------
+// GET /x/v2/history/toview/policy  — 查询稍后再看自动整理设置
+// ---------------------------------------------------------------------------
+
+func ToviewPolicy(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	var settings model.UserSettings
+	database.DB.Where("user_id = ?", userID).First(&settings)
+
+	response.Success(c, gin.H{
+		"auto_remove": settings.ToviewAutoRemove,
+		"expire_days": settings.ToviewExpireDays,
+		"max_size":    settings.ToviewMaxSize,
+	})
+}
+
+// ---------------------------------------------------------------------------
+// POST /x/v2/history/toview/policy/set  — 设置稍后再看自动整理
+// ---------------------------------------------------------------------------
+
+func ToviewPolicySet(c *gin.Context) {
+	userID := middleware.GetUserID(c)
+
+	updates := map[string]interface{}{}
+	for form, column := range map[string]string{
+		"auto_remove": "toview_auto_remove",
+		"expire_days": "toview_expire_days",
+		"max_size":    "toview_max_size",
+	} {
+		str, ok := c.GetPostForm(form)
+		if !ok {
+			continue
+		}
+		v, err := strconv.Atoi(str)
+		if err != nil || v < 0 || (form == "auto_remove" && v > 1) {
+			response.BadRequest(c, "invalid "+form)
+			return
+		}
+		updates[column] = v
+	}
+
+	var settings model.UserSettings
+	result := database.DB.Where("user_id = ?", userID).First(&settings)
+	if result.Error == gorm.ErrRecordNotFound {
+		settings = model.UserSettings{UserID: userID}
+		database.DB.Create(&settings)
+	}
+	if len(updates) > 0 {
+		database.DB.Model(&settings).Updates(updates)
+	}
+
+	database.DB.Where("user_id = ?", userID).First(&settings)
+	removed := pruneWatchLater(&settings)
+
+	response.Success(c, gin.H{"removed": removed})
+}
@@ -0,0 +1,46 @@
+package handler
+
+import (
+	"reflect"
+	"sort"
+	"testing"
+	"time"
+
+	"piliminusb/model"
+)
+
+func TestWatchLaterEvictions(t *testing.T) {
+	now := time.Date(2026, 10, 19, 12, 0, 0, 0, time.UTC)
+	day := int64(24 * 60 * 60)
+	items := []model.WatchLater{
+		{ID: 1, AddedAt: now.Unix() - 10*day, Viewed: 1},
+		{ID: 2, AddedAt: now.Unix() - 5*day},
+		{ID: 3, AddedAt: now.Unix() - 2*day},
+		{ID: 4, AddedAt: now.Unix() - 2*day},
+		{ID: 5, AddedAt: now.Unix(), Viewed: 1},
+	}
+
+	tests := []struct {
+		name     string
+		settings model.UserSettings
+		want     []uint
+	}{
+		{"no rules", model.UserSettings{}, nil},
+		{"auto remove watched", model.UserSettings{ToviewAutoRemove: 1}, []uint{1, 5}},
+		{"expire after 3 days", model.UserSettings{ToviewExpireDays: 3}, []uint{1, 2}},
+		{"cap keeps newest", model.UserSettings{ToviewMaxSize: 2}, []uint{1, 2, 3}},
+		{"cap at size", model.UserSettings{ToviewMaxSize: 5}, nil},
+		{"cap after other rules", model.UserSettings{ToviewAutoRemove: 1, ToviewMaxSize: 2}, []uint{1, 2, 5}},
+		{"all rules", model.UserSettings{ToviewAutoRemove: 1, ToviewExpireDays: 3, ToviewMaxSize: 1}, []uint{1, 2, 3, 5}},
+	}
+	for _, tt := range tests {
+		got := watchLaterEvictions(&tt.settings, items, now)
+		sort.Slice(got, func(i, j int) bool { return got[i] < got[j] })
+		if len(got) == 0 {
+			got = nil
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("%s: evicted %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,200 @@
+package imagecache
+
+import (
+	"bytes"
+	"fmt"
+	"image"
+	_ "image/gif"
+	"image/jpeg"
+	"image/png"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"golang.org/x/image/draw"
+	_ "golang.org/x/image/webp"
+)
+
+// variantSides are the sizes a requested side snaps up to, so arbitrary
+// "@{w}w_{h}h" suffixes map onto a small set of stored files. Larger
+// requests are clamped to the last one; the CDN does the same.
+var variantSides = []int{64, 128, 240, 320, 480, 640, 960, 1280, 2048}
+
+// maxDecodePixels bounds the images decoded for resizing; a small file can
+// declare huge dimensions and exhaust memory once decoded.
+const maxDecodePixels = 40_000_000
+
+// maxVariantsPerObject bounds the variants stored for one original. Further
+// sizes are still rendered, just not kept.
+const maxVariantsPerObject = 16
+
+// variantSpec is the parsed "@{w}w_{h}h_1c" suffix of a Bilibili image URL.
+// A zero side is derived from the other keeping the aspect ratio; crop fills
+// both sides and trims the overflow instead of fitting inside them.
+type variantSpec struct {
+	Width, Height int
+	Crop          bool
+}
+
+func (v variantSpec) empty() bool {
+	return v.Width == 0 && v.Height == 0
+}
+
+// parseVariant parses e.g. "320w_200h_1c.webp". Unknown parameters (quality,
+// format) are ignored: variants are always served as JPEG or PNG.
+func parseVariant(s string) variantSpec {
+	if i := strings.IndexByte(s, '.'); i >= 0 {
+		s = s[:i]
+	}
+	var v variantSpec
+	for _, p := range strings.Split(s, "_") {
+		if len(p) < 2 {
+			continue
+		}
+		n, err := strconv.Atoi(p[:len(p)-1])
+		if err != nil || n <= 0 {
+			continue
+		}
+		switch p[len(p)-1] {
+		case 'w':
+			v.Width = snapSide(n)
+		case 'h':
+			v.Height = snapSide(n)
+		case 'c':
+			v.Crop = n == 1
+		}
+	}
+	return v
+}
+
+// snapSide rounds a requested side up to the next of variantSides.
+func snapSide(n int) int {
+	for _, s := range variantSides {
+		if n <= s {
+			return s
+		}
+	}
+	return variantSides[len(variantSides)-1]
+}
+
+// variant returns the original hash resized to v, rendering and storing it on
+// first use.
+func variant(hash string, v variantSpec) ([]byte, string, error) {
+	name := fmt.Sprintf("%s_%dx%d", hash, v.Width, v.Height)
+	if v.Crop {
+		name += "c"
+	}
+	path := filepath.Join(dir, "variants", hash[:2], name)
+	if data, err := os.ReadFile(path); err == nil {
+		return data, contentTypeOf(data), nil
+	}
+	store := countVariants(hash) < maxVariantsPerObject
+
+	res, err, _ := inflight.Do(path, func() (interface{}, error) {
+		src, err := os.ReadFile(objectPath(hash))
+		if err != nil {
+			return nil, err
+		}
+		data, err := resize(src, v)
+		if err != nil || !store {
+			return data, err
+		}
+		return data, writeFile(path, data)
+	})
+	if err != nil {
+		return nil, "", err
+	}
+	data := res.([]byte)
+	return data, contentTypeOf(data), nil
+}
+
+// countVariants returns how many variants of hash are stored.
+func countVariants(hash string) int {
+	entries, _ := os.ReadDir(filepath.Join(dir, "variants", hash[:2]))
+	n := 0
+	for _, e := range entries {
+		if strings.HasPrefix(e.Name(), hash+"_") {
+			n++
+		}
+	}
+	return n
+}
+
+// resize decodes an image, scales it to v and re-encodes it: PNG when the
+// source may have transparency, JPEG otherwise. Animated GIFs keep their
+// first frame.
+func resize(src []byte, v variantSpec) ([]byte, error) {
+	cfg, _, err := image.DecodeConfig(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("imagecache: decode: %w", err)
+	}
+	if int64(cfg.Width)*int64(cfg.Height) > maxDecodePixels {
+		return nil, fmt.Errorf("imagecache: %dx%d image is too large to resize", cfg.Width, cfg.Height)
+	}
+	img, format, err := image.Decode(bytes.NewReader(src))
+	if err != nil {
+		return nil, fmt.Errorf("imagecache: decode: %w", err)
+	}
+	b := img.Bounds()
+	sw, sh := b.Dx(), b.Dy()
+	if sw == 0 || sh == 0 {
+		return nil, fmt.Errorf("imagecache: empty image")
+	}
+
+	w, h := v.Width, v.Height
+	switch {
+	case w == 0:
+		w = sw * h / sh
+	case h == 0:
+		h = sh * w / sw
+	}
+	// Never upscale
+	if w > sw || h > sh {
+		scale := min(float64(sw)/float64(w), float64(sh)/float64(h))
+		w, h = int(float64(w)*scale), int(float64(h)*scale)
+	}
+	w, h = max(w, 1), max(h, 1)
+
+	// Crop: take the largest centered region with the target aspect ratio
+	srcRect := b
+	if v.Crop && v.Width > 0 && v.Height > 0 {
+		if sw*h > sh*w {
+			cw := sh * w / h
+			x := b.Min.X + (sw-cw)/2
+			srcRect = image.Rect(x, b.Min.Y, x+cw, b.Max.Y)
+		} else {
+			ch := sw * h / w
+			y := b.Min.Y + (sh-ch)/2
+			srcRect = image.Rect(b.Min.X, y, b.Max.X, y+ch)
+		}
+	} else if v.Width > 0 && v.Height > 0 {
+		// Fit inside the box
+		if sw*h > sh*w {
+			h = max(sh*w/sw, 1)
+		} else {
+			w = max(sw*h/sh, 1)
+		}
+	}
+
+	dst := image.NewRGBA(image.Rect(0, 0, w, h))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, srcRect, draw.Src, nil)
+
+	var buf bytes.Buffer
+	if format == "png" || format == "gif" || format == "webp" {
+		err = png.Encode(&buf, dst)
+	} else {
+		err = jpeg.Encode(&buf, dst, &jpeg.Options{Quality: 85})
+	}
+	if err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func contentTypeOf(data []byte) string {
+	if bytes.HasPrefix(data, []byte("\x89PNG")) {
+		return "image/png"
+	}
+	return "image/jpeg"
+}
@@ -0,0 +1,260 @@
+// Package imagecache keeps local copies of the Bilibili covers and avatars
+// referenced by stored items, so saved lists keep their thumbnails after a
+// video is deleted or a CDN URL rotates, and clients can load images without
+// contacting Bilibili.
+//
+// Originals are stored content-addressed under <dir>/objects, keyed by the
+// SHA-256 of their bytes; <dir>/urls maps a source URL to the object it was
+// fetched as. Resized variants are derived from the original on demand,
+// with sizes snapped to a fixed set, and kept under <dir>/variants.
+package imagecache
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"golang.org/x/sync/singleflight"
+
+	"piliminusb/config"
+)
+
+// maxImageSize bounds a single fetched image.
+const maxImageSize = 20 << 20
+
+// ErrNotAllowed is returned for URLs outside Bilibili's image CDN; the proxy
+// must not become an open relay.
+var ErrNotAllowed = errors.New("imagecache: host not allowed")
+
+// ErrBadSignature is returned when a proxy URL wasn't issued by ProxyURL.
+// Only images the server itself handed out are fetched and stored, so
+// anonymous callers can't fill the cache with arbitrary CDN images.
+var ErrBadSignature = errors.New("imagecache: bad signature")
+
+var allowedHosts = []string{"hdslb.com", "biliimg.com"}
+
+var (
+	dir      string
+	signKey  []byte
+	client   = &http.Client{Timeout: 20 * time.Second}
+	inflight singleflight.Group
+)
+
+// Init prepares the store directory and URL signing key from the config.
+func Init() error {
+	dir = config.Get().Image.Dir
+	mac := hmac.New(sha256.New, []byte(config.Get().JWT.Secret))
+	mac.Write([]byte("imagecache"))
+	signKey = mac.Sum(nil)
+	for _, sub := range []string{"objects", "urls", "variants"} {
+		if err := os.MkdirAll(filepath.Join(dir, sub), 0o755); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Get returns the image at rawURL, resized if it names a variant such as
+// "...jpg@320w_200h_1c.webp". The original is fetched and stored on first
+// use.
+func Get(rawURL string) (data []byte, contentType string, err error) {
+	base, v, err := parseURL(rawURL)
+	if err != nil {
+		return nil, "", err
+	}
+	hash, err := original(base)
+	if err != nil {
+		return nil, "", err
+	}
+	if v.empty() {
+		data, err = os.ReadFile(objectPath(hash))
+		if err != nil {
+			return nil, "", err
+		}
+		return data, http.DetectContentType(data), nil
+	}
+	return variant(hash, v)
+}
+
+// Warm makes sure the original of rawURL is stored, fetching it if needed.
+func Warm(rawURL string) error {
+	base, _, err := parseURL(rawURL)
+	if err != nil {
+		return err
+	}
+	_, err = original(base)
+	return err
+}
+
+// Cached reports whether the original of rawURL is already stored.
+func Cached(rawURL string) bool {
+	base, _, err := parseURL(rawURL)
+	if err != nil {
+		return false
+	}
+	_, err = os.Stat(urlPath(base))
+	return err == nil
+}
+
+// ProxyURL rewrites an image URL to go through the proxy at publicURL.
+// Anything that isn't a Bilibili image is returned unchanged. The signature
+// covers the original only and url comes last, so clients can still append
+// an "@{w}w_{h}h" variant suffix.
+func ProxyURL(publicURL, rawURL string) string {
+	base, _, err := parseURL(rawURL)
+	if err != nil {
+		return rawURL
+	}
+	return strings.TrimRight(publicURL, "/") + "/img?sig=" + sign(base) + "&url=" + url.QueryEscape(rawURL)
+}
+
+// Verify checks the signature of a proxy request for rawURL.
+func Verify(rawURL, sig string) error {
+	base, _, err := parseURL(rawURL)
+	if err != nil {
+		return err
+	}
+	if !hmac.Equal([]byte(sign(base)), []byte(sig)) {
+		return ErrBadSignature
+	}
+	return nil
+}
+
+func sign(base string) string {
+	mac := hmac.New(sha256.New, signKey)
+	mac.Write([]byte(base))
+	return hex.EncodeToString(mac.Sum(nil)[:16])
+}
+
+// parseURL validates an image URL and splits off its variant suffix. The
+// returned base is the normalized https URL of the original.
+func parseURL(rawURL string) (string, variantSpec, error) {
+	if strings.HasPrefix(rawURL, "//") {
+		rawURL = "https:" + rawURL
+	}
+	u, err := url.Parse(rawURL)
+	if err != nil || u.Host == "" {
+		return "", variantSpec{}, fmt.Errorf("imagecache: invalid url %q", rawURL)
+	}
+	if u.Scheme != "http" && u.Scheme != "https" || !hostAllowed(u.Hostname()) {
+		return "", variantSpec{}, ErrNotAllowed
+	}
+
+	var v variantSpec
+	if i := strings.LastIndex(u.Path, "@"); i >= 0 {
+		v = parseVariant(u.Path[i+1:])
+		u.Path = u.Path[:i]
+	}
+	u.Scheme = "https"
+	u.RawQuery = ""
+	u.Fragment = ""
+	return u.String(), v, nil
+}
+
+func hostAllowed(host string) bool {
+	for _, h := range allowedHosts {
+		if host == h || strings.HasSuffix(host, "."+h) {
+			return true
+		}
+	}
+	return false
+}
+
+// original returns the content hash of the image at base, downloading it the
+// first time.
+func original(base string) (string, error) {
+	if b, err := os.ReadFile(urlPath(base)); err == nil {
+		hash := string(b)
+		if _, err := os.Stat(objectPath(hash)); err == nil {
+			return hash, nil
+		}
+	}
+
+	hash, err, _ := inflight.Do(base, func() (interface{}, error) {
+		data, err := download(base)
+		if err != nil {
+			return "", err
+		}
+		sum := sha256.Sum256(data)
+		hash := hex.EncodeToString(sum[:])
+		if err := writeFile(objectPath(hash), data); err != nil {
+			return "", err
+		}
+		return hash, writeFile(urlPath(base), []byte(hash))
+	})
+	if err != nil {
+		return "", err
+	}
+	return hash.(string), nil
+}
+
+func download(base string) ([]byte, error) {
+	req, err := http.NewRequest("GET", base, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "Mozilla/5.0 (Windows NT 10.0; Win64; x64) AppleWebKit/537.36")
+	req.Header.Set("Referer", "https://www.bilibili.com")
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, fmt.Errorf("imagecache: fetch failed: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("imagecache: fetch %s: %s", base, resp.Status)
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, maxImageSize+1))
+	if err != nil {
+		return nil, fmt.Errorf("imagecache: read failed: %w", err)
+	}
+	if len(data) > maxImageSize {
+		return nil, fmt.Errorf("imagecache: %s is larger than %d bytes", base, maxImageSize)
+	}
+	if !strings.HasPrefix(http.DetectContentType(data), "image/") {
+		return nil, fmt.Errorf("imagecache: %s is not an image", base)
+	}
+	return data, nil
+}
+
+// writeFile writes atomically, so a crash never leaves a truncated image
+// behind a valid name.
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
+
+func objectPath(hash string) string {
+	return filepath.Join(dir, "objects", hash[:2], hash)
+}
+
+func urlPath(base string) string {
+	sum := sha256.Sum256([]byte(base))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(dir, "urls", key[:2], key)
+}
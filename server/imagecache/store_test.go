@@ -0,0 +1,42 @@
+package imagecache
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+)
+
+func TestProxyURLSignature(t *testing.T) {
+	signKey = []byte("test key")
+	proxied := ProxyURL("https://pili.example.com/", "//i0.hdslb.com/bfs/archive/a.jpg")
+
+	// Clients append the variant suffix to whatever URL they were given
+	u, err := url.Parse(proxied + "@320w_200h_1c.webp")
+	if err != nil {
+		t.Fatal(err)
+	}
+	sig, raw := u.Query().Get("sig"), u.Query().Get("url")
+	if err := Verify(raw, sig); err != nil {
+		t.Errorf("Verify(%q) = %v, want nil", raw, err)
+	}
+
+	tests := []struct {
+		raw, sig string
+		want     error
+	}{
+		{"https://i0.hdslb.com/bfs/archive/b.jpg", sig, ErrBadSignature},
+		{raw, "", ErrBadSignature},
+		{raw, strings.Repeat("0", len(sig)), ErrBadSignature},
+		{"https://example.com/a.jpg", sig, ErrNotAllowed},
+	}
+	for _, tt := range tests {
+		if err := Verify(tt.raw, tt.sig); !errors.Is(err, tt.want) {
+			t.Errorf("Verify(%q, %q) = %v, want %v", tt.raw, tt.sig, err, tt.want)
+		}
+	}
+
+	if got := ProxyURL("https://pili.example.com", "https://example.com/a.jpg"); got != "https://example.com/a.jpg" {
+		t.Errorf("ProxyURL rewrote a non-Bilibili URL: %q", got)
+	}
+}
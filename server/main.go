@@ -4,11 +4,13 @@ import (
 	"log"
 
 	"github.com/gin-gonic/gin"
+	"gorm.io/gorm"
 
 	"piliminusb/bilibili"
 	"piliminusb/config"
 	"piliminusb/database"
 	"piliminusb/handler"
+	"piliminusb/imagecache"
 	"piliminusb/middleware"
 	"piliminusb/model"
 	saucsrv "piliminusb/sauc/server"
@@ -19,14 +21,63 @@ func main() {
 
 	// Database
 	database.Init()
-	database.DB.AutoMigrate(&model.User{}, &model.WatchLater{}, &model.WatchHistory{}, &model.UserSettings{}, &model.FavFolder{}, &model.FavResource{}, &model.Following{}, &model.FollowTag{}, &model.FollowTagMember{}, &model.BangumiFollow{})
+	database.DB.AutoMigrate(&model.User{}, &model.WatchLater{}, &model.WatchHistory{}, &model.WatchHistoryPart{}, &model.PlaybackSession{}, &model.UserSettings{}, &model.FavFolder{}, &model.FavResource{}, &model.FavFolderShare{}, &model.FavSubscription{}, &model.UgcSeasonEpisode{}, &model.Following{}, &model.FollowTag{}, &model.FollowTagMember{}, &model.BangumiFollow{}, &model.LostResource{}, &model.VideoCheck{}, &model.SpaceArchive{}, &model.NotifyChannel{}, &model.NotifyRule{}, &model.NotifyEvent{}, &model.NotifyDelivery{})
+
+	// History is now keyed by (user, business, oid); drop the old per-aid unique
+	// index so live rooms and articles can share numeric ids with videos.
+	if database.DB.Migrator().HasIndex(&model.WatchHistory{}, "idx_hist_user_aid") {
+		database.DB.Migrator().DropIndex(&model.WatchHistory{}, "idx_hist_user_aid")
+	}
+
+	// History kids are "business_oid"; older video entries stored the bare aid
+	// (or nothing), which DelHistory can't tell apart from other businesses.
+	database.DB.Model(&model.WatchHistory{}).Where("kid IS NULL OR kid NOT LIKE ?", "%\\_%").
+		Update("kid", gorm.Expr("CONCAT(business, '_', aid)"))
+
+	// Favorites are now keyed by (user, folder, resource, type) so an audio or
+	// article can share its numeric id with a video.
+	if database.DB.Migrator().HasIndex(&model.FavResource{}, "idx_favr_user_media_res") {
+		database.DB.Migrator().DropIndex(&model.FavResource{}, "idx_favr_user_media_res")
+	}
+
+	// Local cover/avatar cache, optionally used for every image URL we return
+	if err := imagecache.Init(); err != nil {
+		log.Fatalf("failed to prepare image cache: %v", err)
+	}
+	if cfg.Image.RewriteURLs && cfg.Image.PublicURL != "" {
+		model.ImageURL = func(u string) string {
+			return imagecache.ProxyURL(cfg.Image.PublicURL, u)
+		}
+	}
 
 	// Start background task: periodically fetch UP videos from Bilibili
 	bilibili.StartBackgroundRefresh(func() []int64 {
 		var mids []int64
-		database.DB.Model(&model.Following{}).Distinct("mid").Pluck("mid", &mids)
+		database.DB.Model(&model.Following{}).Where("attribute IN ?", model.RelationFollowed).
+			Distinct("mid").Pluck("mid", &mids)
 		return mids
-	})
+	}, handler.SaveSpaceVideos)
+
+	// Start background task: refresh names, avatars and verification of followed UPs
+	bilibili.StartProfileRefresh(handler.StaleProfileMids, handler.SaveUserProfile)
+
+	// Start background task: enforce per-user history retention settings
+	handler.StartHistoryPruner()
+
+	// Start background task: enforce per-user watch later maintenance settings
+	handler.StartWatchLaterPruner()
+
+	// Start background task: refresh episode lists of subscribed UGC seasons
+	handler.StartSeasonRefresher()
+
+	// Start background task: flag stored videos that were deleted upstream
+	handler.StartVideoValidator()
+
+	// Start background task: download covers and avatars of stored items
+	handler.StartImageWarmer()
+
+	// Start background task: send and retry new-upload notifications
+	handler.StartNotifier()
 
 	// Router
 	r := gin.Default()
@@ -38,6 +89,16 @@ func main() {
 		auth.POST("/login", handler.Login)
 	}
 
+	// Read-only share links for public favorite folders
+	r.GET("/share/fav/:token", handler.SharedFavFolder)
+
+	// Cached Bilibili covers and avatars, loaded by image widgets without auth;
+	// only URLs signed by model.ImageURL are served
+	r.GET("/img", handler.ImageProxy)
+
+	// Followed-UP feed for feed readers, authenticated by the per-user feed token
+	r.GET("/feed/:token", handler.FollowFeed)
+
 	// Protected routes (all future Phase 1-4 endpoints go here)
 	api := r.Group("/")
 	api.Use(middleware.Auth())
@@ -47,19 +108,32 @@ func main() {
 		api.POST("/x/v2/history/toview/add", handler.ToviewAdd)
 		api.POST("/x/v2/history/toview/v2/dels", handler.ToviewDel)
 		api.POST("/x/v2/history/toview/clear", handler.ToviewClear)
+		api.POST("/x/v2/history/toview/sort", handler.ToviewSort)
+		api.POST("/x/v2/history/toview/pin", handler.ToviewPin)
+		api.GET("/x/v2/history/toview/policy", handler.ToviewPolicy)
+		api.POST("/x/v2/history/toview/policy/set", handler.ToviewPolicySet)
 		api.GET("/x/v2/medialist/resource/list", handler.MediaList)
 
 		// Phase 2: History
 		api.GET("/x/web-interface/history/cursor", handler.HistoryList)
 		api.GET("/x/web-interface/history/search", handler.SearchHistory)
 		api.POST("/x/v2/history/delete", handler.DelHistory)
+		api.POST("/x/v2/history/delete/range", handler.DelHistoryRange)
 		api.POST("/x/v2/history/clear", handler.ClearHistory)
 		api.POST("/x/v2/history/shadow/set", handler.HistoryShadowSet)
 		api.GET("/x/v2/history/shadow", handler.HistoryShadow)
+		api.GET("/x/v2/history/retention", handler.HistoryRetention)
+		api.POST("/x/v2/history/retention/set", handler.HistoryRetentionSet)
 		api.POST("/x/click-interface/web/heartbeat", handler.HeartBeat)
 		api.POST("/x/v2/history/report", handler.HistoryReport)
+		api.POST("/x/v2/history/report/live", handler.HistoryReportLive)
+		api.POST("/x/v2/history/report/article", handler.HistoryReportArticle)
+		api.POST("/x/v2/history/report/cheese", handler.HistoryReportCheese)
 		api.POST("/x/v1/medialist/history", handler.MedialistHistory)
 		api.GET("/x/v2/history/progress", handler.HistoryProgress)
+		api.GET("/x/v2/history/sessions", handler.HistorySessions)
+		api.GET("/x/v2/history/stats", handler.HistoryStats)
+		api.GET("/x/v2/history/stats/year", handler.HistoryYearReview)
 
 		// Phase 3: Favorites — Folder Management
 		api.GET("/x/v3/fav/folder/created/list-all", handler.AllFavFolders)
@@ -69,6 +143,16 @@ func main() {
 		api.POST("/x/v3/fav/folder/edit", handler.EditFavFolder)
 		api.POST("/x/v3/fav/folder/del", handler.DelFavFolder)
 		api.POST("/x/v3/fav/folder/sort", handler.SortFavFolder)
+		api.GET("/x/v3/fav/folder/collected/list", handler.CollectedFavFolders)
+		api.GET("/x/v3/fav/folder/share/link", handler.FavFolderShareLink)
+		api.GET("/x/v3/fav/folder/share/list", handler.FavFolderShareList)
+		api.POST("/x/v3/fav/folder/share/add", handler.FavFolderShareAdd)
+		api.POST("/x/v3/fav/folder/share/del", handler.FavFolderShareDel)
+		api.POST("/x/v3/fav/folder/fav", handler.FavFolderSubscribe)
+		api.POST("/x/v3/fav/folder/unfav", handler.FavFolderUnsubscribe)
+		api.POST("/x/v3/fav/season/fav", handler.FavSeasonSubscribe)
+		api.POST("/x/v3/fav/season/unfav", handler.FavSeasonUnsubscribe)
+		api.GET("/x/space/fav/season/list", handler.FavSeasonList)
 
 		// Phase 3: Favorites — Resource Management
 		api.GET("/x/v3/fav/resource/list", handler.ListFavResources)
@@ -78,6 +162,7 @@ func main() {
 		api.POST("/x/v3/fav/resource/move", handler.MoveFavResource)
 		api.POST("/x/v3/fav/resource/clean", handler.CleanFavResource)
 		api.POST("/x/v3/fav/resource/sort", handler.SortFavResource)
+		api.GET("/x/v3/fav/resource/lost", handler.LostResources)
 
 		// Phase 3: Watch Later ↔ Favorites Cross-Operations
 		api.POST("/x/v2/history/toview/copy", handler.ToviewCopy)
@@ -87,13 +172,20 @@ func main() {
 		api.GET("/x/relation", handler.Relation)
 		api.GET("/x/relation/followings", handler.Followings)
 		api.GET("/x/relation/followings/search", handler.FollowingsSearch)
+		api.GET("/x/relation/whispers", handler.Whispers)
+		api.GET("/x/relation/blacks", handler.Blacks)
 		api.POST("/x/relation/modify", handler.RelationMod)
+		api.POST("/x/relation/batch/modify", handler.RelationBatchMod)
+		api.GET("/x/relation/suggest", handler.FollowSuggest)
+		api.GET("/x/relation/inactive", handler.FollowInactive)
 		api.GET("/x/relation/tags", handler.FollowTags)
 		api.GET("/x/relation/tag", handler.FollowTagMembers)
 		api.POST("/x/relation/tag/create", handler.CreateFollowTag)
 		api.POST("/x/relation/tag/update", handler.UpdateFollowTag)
 		api.POST("/x/relation/tag/del", handler.DelFollowTag)
 		api.POST("/x/relation/tags/addUsers", handler.AddUsersToTag)
+		api.POST("/x/relation/tags/copyUsers", handler.CopyUsersToTag)
+		api.POST("/x/relation/tags/moveUsers", handler.MoveUsersToTag)
 		api.POST("/x/relation/tag/special/add", handler.AddSpecial)
 		api.POST("/x/relation/tag/special/del", handler.DelSpecial)
 
@@ -103,9 +195,32 @@ func main() {
 		api.POST("/pgc/web/follow/del", handler.PgcDel)
 		api.POST("/pgc/web/follow/status/update", handler.PgcUpdate)
 
+		// Offline UP space, served from the background crawl
+		api.GET("/x/space/wbi/arc/search", handler.SpaceArcSearch)
+		api.GET("/x/v2/space/archive/cursor", handler.SpaceArchiveCursor)
+		api.GET("/x/web-interface/card", handler.SpaceCard)
+
+		// Privacy proxy for anonymous Bilibili reads, shared cache across users
+		api.GET("/proxy/:host/*path", handler.BiliProxy)
+
+		// Unified search across history, favorites and watch later
+		api.GET("/x/v2/library/search", handler.LibrarySearch)
+
 		// Phase 5: Dynamics Feed
 		api.GET("/x/polymer/web-dynamic/v1/feed/all", handler.DynamicFeed)
 		api.GET("/x/polymer/web-dynamic/v1/portal", handler.DynamicPortal)
+		api.GET("/x/feed/token", handler.FeedToken)
+
+		// Notifications for new uploads of followed UPs
+		api.GET("/x/notify/channels", handler.NotifyChannels)
+		api.POST("/x/notify/channel/add", handler.NotifyChannelAdd)
+		api.POST("/x/notify/channel/del", handler.NotifyChannelDel)
+		api.POST("/x/notify/channel/test", handler.NotifyChannelTest)
+		api.GET("/x/notify/rules", handler.NotifyRules)
+		api.POST("/x/notify/rule/add", handler.NotifyRuleAdd)
+		api.POST("/x/notify/rule/del", handler.NotifyRuleDel)
+		api.GET("/x/notify/events", handler.NotifyEvents)
+		api.GET("/x/notify/stream", handler.NotifyStream)
 
 		// sauc: subtitle / ASR service (merged from former sauc_go)
 		saucSvc := saucsrv.New(cfg.Sauc)
@@ -0,0 +1,15 @@
+package model
+
+import "time"
+
+// FavFolderShare grants another local user access to a favorite folder.
+// Folders are addressed by (owner, media_id) since media ids are per user.
+type FavFolderShare struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	OwnerID   uint      `gorm:"not null;uniqueIndex:idx_favshare_folder_user" json:"-"`
+	MediaID   int64     `gorm:"not null;uniqueIndex:idx_favshare_folder_user" json:"media_id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_favshare_folder_user;index" json:"user_id"`
+	Role      string    `gorm:"size:10;not null" json:"role"` // viewer | editor
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
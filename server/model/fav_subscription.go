@@ -0,0 +1,71 @@
+package model
+
+import "time"
+
+// Subscription types, matching the official collected-folder list.
+const (
+	FavSubFolder    = 11 // another local user's public favorite folder
+	FavSubUgcSeason = 21 // a Bilibili UGC season (合集)
+)
+
+// FavSubscription is a folder or UGC season a user has subscribed to
+// (收藏的合集/订阅). For folders OwnerID and TargetID address the folder;
+// for seasons TargetID is the season id and the fields below are a snapshot
+// refreshed in the background.
+type FavSubscription struct {
+	ID         uint      `gorm:"primaryKey" json:"-"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_favsub_user_target" json:"-"`
+	Type       int       `gorm:"not null;uniqueIndex:idx_favsub_user_target" json:"type"`
+	OwnerID    uint      `gorm:"not null;default:0;uniqueIndex:idx_favsub_user_target" json:"-"`
+	TargetID   int64     `gorm:"not null;uniqueIndex:idx_favsub_user_target;index" json:"id"`
+	Title      string    `gorm:"size:200" json:"title"`
+	Cover      string    `gorm:"size:500" json:"cover"`
+	Intro      string    `gorm:"size:500" json:"intro"`
+	UpperMid   int64     `json:"upper_mid"`
+	UpperName  string    `gorm:"size:100" json:"upper_name"`
+	UpperFace  string    `gorm:"size:500" json:"upper_face"`
+	MediaCount int       `json:"media_count"`
+	SubTime    int64     `gorm:"not null" json:"ctime"`
+	CreatedAt  time.Time `json:"-"`
+	UpdatedAt  time.Time `json:"-"`
+}
+
+// UgcSeasonEpisode is a cached episode of a subscribed UGC season, shared by
+// every subscriber.
+type UgcSeasonEpisode struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	SeasonID  int64     `gorm:"not null;uniqueIndex:idx_ugcep_season_aid;index:idx_ugcep_season_pos" json:"season_id"`
+	Aid       int64     `gorm:"not null;uniqueIndex:idx_ugcep_season_aid" json:"aid"`
+	Position  int       `gorm:"not null;index:idx_ugcep_season_pos" json:"position"`
+	Bvid      string    `gorm:"size:20" json:"bvid"`
+	Title     string    `gorm:"size:500" json:"title"`
+	Cover     string    `gorm:"size:500" json:"cover"`
+	Duration  int       `json:"duration"`
+	Pubtime   int64     `json:"pubtime"`
+	Play      int64     `json:"play"`
+	Danmaku   int64     `json:"danmaku"`
+	UpperMid  int64     `json:"upper_mid"`
+	UpperName string    `gorm:"size:100" json:"upper_name"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// ToBiliJSON converts to the official season media item JSON.
+func (e *UgcSeasonEpisode) ToBiliJSON() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       e.Aid,
+		"title":    e.Title,
+		"cover":    ImageURL(e.Cover),
+		"duration": e.Duration,
+		"pubtime":  e.Pubtime,
+		"bvid":     e.Bvid,
+		"upper": map[string]interface{}{
+			"mid":  e.UpperMid,
+			"name": e.UpperName,
+		},
+		"cnt_info": map[string]interface{}{
+			"play":    e.Play,
+			"danmaku": e.Danmaku,
+		},
+	}
+}
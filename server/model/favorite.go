@@ -1,6 +1,9 @@
 package model
 
-import "time"
+import (
+	"fmt"
+	"time"
+)
 
 // ---------------------------------------------------------------------------
 // Phase 3: Favorites
@@ -19,6 +22,8 @@ type FavFolder struct {
 	Mtime      int64     `json:"mtime"`
 	SortOrder  int       `gorm:"default:0" json:"sort_order"`
 	IsDefault  int       `gorm:"default:0" json:"is_default"`
+	Privacy    int       `gorm:"default:1" json:"privacy"` // 0: public, 1: private (default)
+	ShareToken string    `gorm:"size:32;index" json:"-"`   // read-only share link; only served while public
 	CreatedAt  time.Time `json:"-"`
 	UpdatedAt  time.Time `json:"-"`
 }
@@ -29,9 +34,9 @@ func (f *FavFolder) ToBiliJSON(ownerMid int64) map[string]interface{} {
 		"id":          f.MediaID,
 		"fid":         f.MediaID,
 		"mid":         ownerMid,
-		"attr":        0,
+		"attr":        f.Attr(),
 		"title":       f.Title,
-		"cover":       f.Cover,
+		"cover":       ImageURL(f.Cover),
 		"upper": map[string]interface{}{
 			"mid":  ownerMid,
 			"name": "",
@@ -50,6 +55,16 @@ func (f *FavFolder) ToBiliJSON(ownerMid int64) map[string]interface{} {
 	}
 }
 
+// Attr returns the official attribute bits: bit 0 set for private folders,
+// bit 1 set for every folder but the default one.
+func (f *FavFolder) Attr() int {
+	attr := f.Privacy & 1
+	if f.IsDefault == 0 {
+		attr |= 2
+	}
+	return attr
+}
+
 // ToBiliJSONWithFavState returns folder info with fav_state indicating the
 // given resource is in this folder (1) or not (0).
 func (f *FavFolder) ToBiliJSONWithFavState(ownerMid int64, favState int) map[string]interface{} {
@@ -58,13 +73,31 @@ func (f *FavFolder) ToBiliJSONWithFavState(ownerMid int64, favState int) map[str
 	return m
 }
 
-// FavResource represents a single resource (video) inside a favorite folder.
+// Favorite resource types, as used by the official fav API. Articles have no
+// fav folder type upstream, so they can't be stored here.
+const (
+	FavTypeVideo     = 2  // 视频稿件 (aid)
+	FavTypeAudio     = 12 // 音频 (au id)
+	FavTypeUgcSeason = 21 // 视频合集 (season id)
+	FavTypeOgv       = 24 // 番剧/影视 (ep id)
+)
+
+// FavTypeSupported reports whether t is one of the FavType* constants.
+func FavTypeSupported(t int) bool {
+	switch t {
+	case FavTypeVideo, FavTypeAudio, FavTypeUgcSeason, FavTypeOgv:
+		return true
+	}
+	return false
+}
+
+// FavResource represents a single resource inside a favorite folder.
 type FavResource struct {
 	ID           uint      `gorm:"primaryKey" json:"-"`
-	UserID       uint      `gorm:"not null;uniqueIndex:idx_favr_user_media_res" json:"-"`
-	MediaID      int64     `gorm:"not null;uniqueIndex:idx_favr_user_media_res;index:idx_favr_list" json:"media_id"`
-	ResourceID   int64     `gorm:"not null;uniqueIndex:idx_favr_user_media_res" json:"resource_id"`
-	ResourceType int       `gorm:"default:2" json:"resource_type"` // 2=video
+	UserID       uint      `gorm:"not null;uniqueIndex:idx_favr_user_media_res_type" json:"-"`
+	MediaID      int64     `gorm:"not null;uniqueIndex:idx_favr_user_media_res_type;index:idx_favr_list" json:"media_id"`
+	ResourceID   int64     `gorm:"not null;uniqueIndex:idx_favr_user_media_res_type" json:"resource_id"`
+	ResourceType int       `gorm:"default:2;uniqueIndex:idx_favr_user_media_res_type" json:"resource_type"` // see FavType*
 	Title        string    `gorm:"size:500" json:"title"`
 	Cover        string    `gorm:"size:500" json:"cover"`
 	Intro        string    `gorm:"size:500" json:"intro"`
@@ -74,19 +107,37 @@ type FavResource struct {
 	Bvid         string    `gorm:"size:20" json:"bvid"`
 	Pubtime      int64     `json:"pubtime"`
 	FavTime      int64     `gorm:"not null;index:idx_favr_list" json:"fav_time"`
-	SortOrder    int       `gorm:"default:0" json:"sort_order"`
+	SortOrder    int       `gorm:"default:0" json:"sort_order"` // manual position from 1; 0 = added since the last sort, listed first
+	Play         int64     `gorm:"default:0" json:"play"`
 	Cid          int64     `json:"cid"`
+	SeasonID     int64     `json:"season_id"`                 // OGV episodes: the bangumi season
+	TypeName     string    `gorm:"size:20" json:"type_name"` // OGV episodes: 番剧, 电影, ...
+	Invalid      bool      `gorm:"default:false" json:"invalid"` // deleted upstream, see LostResource
 	CreatedAt    time.Time `json:"-"`
 	UpdatedAt    time.Time `json:"-"`
 }
 
+// Link returns the web page of the resource.
+func (r *FavResource) Link() string {
+	switch r.ResourceType {
+	case FavTypeAudio:
+		return fmt.Sprintf("https://www.bilibili.com/audio/au%d", r.ResourceID)
+	case FavTypeOgv:
+		return fmt.Sprintf("https://www.bilibili.com/bangumi/play/ep%d", r.ResourceID)
+	case FavTypeUgcSeason:
+		return fmt.Sprintf("https://space.bilibili.com/%d/channel/collectiondetail?sid=%d", r.UpperMid, r.ResourceID)
+	default:
+		return fmt.Sprintf("https://www.bilibili.com/video/%s", r.Bvid)
+	}
+}
+
 // ToBiliJSON converts to Bilibili-compatible resource item JSON.
 func (r *FavResource) ToBiliJSON() map[string]interface{} {
-	return map[string]interface{}{
+	m := map[string]interface{}{
 		"id":       r.ResourceID,
 		"type":     r.ResourceType,
 		"title":    r.Title,
-		"cover":    r.Cover,
+		"cover":    ImageURL(r.Cover),
 		"intro":    r.Intro,
 		"page":     0,
 		"duration": r.Duration,
@@ -95,10 +146,10 @@ func (r *FavResource) ToBiliJSON() map[string]interface{} {
 			"name": r.UpperName,
 			"face": "",
 		},
-		"attr": 0,
+		"attr": invalidAttr(r.Invalid),
 		"cnt_info": map[string]interface{}{
 			"collect":   0,
-			"play":      0,
+			"play":      r.Play,
 			"thumb_up":  0,
 			"thumb_down": 0,
 			"share":     0,
@@ -106,40 +157,57 @@ func (r *FavResource) ToBiliJSON() map[string]interface{} {
 			"danmaku":   0,
 			"coin":      0,
 		},
-		"link":    "",
+		"link":    r.Link(),
 		"ctime":   r.FavTime,
 		"pubtime": r.Pubtime,
 		"fav_time": r.FavTime,
 		"bvid":    r.Bvid,
 		"bv_id":   r.Bvid,
-		"ugc": map[string]interface{}{
-			"first_cid": r.Cid,
-		},
 	}
+
+	switch r.ResourceType {
+	case FavTypeVideo:
+		m["page"] = 1
+		m["ugc"] = map[string]interface{}{"first_cid": r.Cid}
+	case FavTypeOgv:
+		m["ogv"] = map[string]interface{}{
+			"type_name": r.TypeName,
+			"type_id":   0,
+			"season_id": r.SeasonID,
+		}
+		m["ugc"] = map[string]interface{}{"first_cid": r.Cid}
+	}
+	return m
 }
 
 // ToMediaListJSON converts to MediaList-compatible JSON map.
 // This matches the format expected by MediaListItemModel.fromJson on the client.
 func (r *FavResource) ToMediaListJSON() map[string]interface{} {
+	// Only videos and OGV episodes have a playable part
+	pages := []map[string]interface{}{}
+	if r.ResourceType == FavTypeVideo || r.ResourceType == FavTypeOgv {
+		pages = append(pages, map[string]interface{}{"id": r.Cid, "title": "", "page": 1})
+	}
+
 	return map[string]interface{}{
 		"id":       r.ResourceID,
 		"title":    r.Title,
-		"cover":    r.Cover,
+		"cover":    ImageURL(r.Cover),
 		"duration": r.Duration,
 		"pubtime":  r.Pubtime,
 		"bv_id":    r.Bvid,
 		"type":     r.ResourceType,
+		"attr":     invalidAttr(r.Invalid),
 		"upper": map[string]interface{}{
 			"mid":  r.UpperMid,
 			"name": r.UpperName,
 			"face": "",
 		},
 		"cnt_info": map[string]interface{}{
-			"play":    0,
+			"play":    r.Play,
 			"danmaku": 0,
 		},
-		"pages": []map[string]interface{}{
-			{"id": r.Cid, "title": "", "page": 1},
-		},
+		"link":  r.Link(),
+		"pages": pages,
 	}
 }
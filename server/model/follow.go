@@ -6,7 +6,19 @@ import "time"
 // Phase 4: Following & Bangumi
 // ---------------------------------------------------------------------------
 
-// Following represents a user-followed account (UP主).
+// Relation attributes, as reported by /x/relation.
+const (
+	RelationWhisper = 1   // 悄悄关注
+	RelationFollow  = 2   // 关注
+	RelationBlack   = 128 // 拉黑
+)
+
+// RelationFollowed lists the attributes that count as following an UP, for
+// feeds and crawling; whispers are hidden from the followings list only.
+var RelationFollowed = []int{RelationWhisper, RelationFollow}
+
+// Following records the user's relation with an account (UP主): followed,
+// whisper-followed or blocked, per Attribute.
 type Following struct {
 	ID          uint      `gorm:"primaryKey" json:"-"`
 	UserID      uint      `gorm:"not null;uniqueIndex:idx_follow_user_mid" json:"-"`
@@ -15,9 +27,15 @@ type Following struct {
 	Face        string    `gorm:"size:500" json:"face"`
 	Sign        string    `gorm:"size:500" json:"sign"`
 	IsSpecial   int       `gorm:"default:0" json:"special"`            // 1 = special follow
-	Attribute   int       `gorm:"default:2" json:"attribute"`          // 2 = followed
+	Attribute   int       `gorm:"default:2" json:"attribute"`          // see Relation*
 	MTime       int64     `json:"mtime"`                               // follow timestamp
 	OfficialType int      `gorm:"default:-1" json:"-"`
+	OfficialDesc string   `gorm:"size:500" json:"-"`
+	VipType     int       `gorm:"default:0" json:"-"`
+	VipStatus   int       `gorm:"default:0" json:"-"`
+	VipDueDate  int64     `gorm:"default:0" json:"-"`              // ms
+	Deactivated bool      `gorm:"default:false" json:"-"`          // account closed upstream
+	ProfileAt   int64     `gorm:"default:0;index" json:"-"`        // last profile refresh
 	SortOrder   int       `gorm:"default:0" json:"-"`
 	CreatedAt   time.Time `json:"-"`
 	UpdatedAt   time.Time `json:"-"`
@@ -31,23 +49,24 @@ func (f *Following) ToBiliJSON() map[string]interface{} {
 		"mtime":          f.MTime,
 		"special":        f.IsSpecial,
 		"uname":          f.Name,
-		"face":           f.Face,
+		"face":           ImageURL(f.Face),
 		"sign":           f.Sign,
 		"face_nft":       0,
 		"official_verify": map[string]interface{}{
 			"type": f.OfficialType,
-			"desc": "",
+			"desc": f.OfficialDesc,
 		},
 		"vip": map[string]interface{}{
-			"vipType":       0,
-			"vipDueDate":    0,
-			"vipStatus":     0,
+			"vipType":       f.VipType,
+			"vipDueDate":    f.VipDueDate,
+			"vipStatus":     f.VipStatus,
 			"themeType":     0,
 			"avatar_subscript": 0,
 		},
 		"nft_icon": "",
 		"rec_reason": "",
 		"track_id":   "",
+		"deactivated": f.Deactivated,
 	}
 }
 
@@ -111,7 +130,7 @@ func (b *BangumiFollow) ToBiliJSON() map[string]interface{} {
 		"season_type":  b.SeasonType,
 		"season_type_name": seasonTypeName(b.SeasonType),
 		"title":        b.Title,
-		"cover":        b.Cover,
+		"cover":        ImageURL(b.Cover),
 		"total_count":  b.TotalCount,
 		"badge":        "",
 		"badge_type":   0,
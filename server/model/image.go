@@ -0,0 +1,17 @@
+package model
+
+// ImageURL maps a stored cover or avatar URL to the one put in API
+// responses. It is the identity unless main points it at the image proxy.
+var ImageURL = func(u string) string { return u }
+
+// imageURLs applies ImageURL to a list of URLs.
+func imageURLs(urls []string) []string {
+	if urls == nil {
+		return nil
+	}
+	out := make([]string, len(urls))
+	for i, u := range urls {
+		out[i] = ImageURL(u)
+	}
+	return out
+}
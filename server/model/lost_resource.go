@@ -0,0 +1,50 @@
+package model
+
+import "time"
+
+// AttrInvalid is the bit of a resource's attr marking it as deleted upstream
+// (失效), as the official fav and medialist APIs report it.
+const AttrInvalid = 1
+
+func invalidAttr(invalid bool) int {
+	if invalid {
+		return AttrInvalid
+	}
+	return 0
+}
+
+// Where a lost resource was removed from.
+const (
+	LostFromFav    = "fav"
+	LostFromToview = "toview"
+)
+
+// LostResource is the last known metadata of an invalid video, saved when it
+// is cleaned out of a favorite folder or watch later, so the user
+// can still tell what was lost.
+type LostResource struct {
+	ID           uint      `gorm:"primaryKey" json:"-"`
+	UserID       uint      `gorm:"not null;index:idx_lost_user_removed" json:"-"`
+	Source       string    `gorm:"size:20;not null" json:"source"`
+	MediaID      int64     `json:"media_id"` // favorite folder, for source "fav"
+	ResourceID   int64     `gorm:"not null" json:"resource_id"`
+	ResourceType int       `gorm:"default:2" json:"resource_type"`
+	Bvid         string    `gorm:"size:20" json:"bvid"`
+	Title        string    `gorm:"size:500" json:"title"`
+	Cover        string    `gorm:"size:500" json:"cover"`
+	Duration     int       `json:"duration"`
+	UpperMid     int64     `json:"upper_mid"`
+	UpperName    string    `gorm:"size:100" json:"upper_name"`
+	Pubtime      int64     `json:"pubtime"`
+	AddedAt      int64     `json:"added_at"` // fav/add/view time in its source
+	RemovedAt    int64     `gorm:"not null;index:idx_lost_user_removed" json:"removed_at"`
+	CreatedAt    time.Time `json:"-"`
+}
+
+// VideoCheck records when a stored video was last verified upstream, which
+// drives the background validator's round-robin.
+type VideoCheck struct {
+	Aid       int64 `gorm:"primaryKey;autoIncrement:false"`
+	Invalid   bool  `gorm:"default:false"`
+	CheckedAt int64 `gorm:"not null;index"`
+}
@@ -0,0 +1,133 @@
+package model
+
+import (
+	"strings"
+	"time"
+)
+
+// Rule scopes: which followed UPs a rule watches.
+const (
+	NotifyScopeAll     = "all"
+	NotifyScopeSpecial = "special" // special follows only
+	NotifyScopeTag     = "tag"     // members of one follow tag
+)
+
+// Delivery states.
+const (
+	DeliveryPending = "pending"
+	DeliverySent    = "sent"
+	DeliveryFailed  = "failed" // gave up after the last retry
+)
+
+// NotifyChannel is a user-configured push destination. Kind picks the sender
+// in package notify: "webhook" (JSON POST, signed with HMAC-SHA256 of Secret),
+// "ntfy" (topic URL, Secret is an access token) or "gotify" (server URL,
+// Secret is the app token).
+type NotifyChannel struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;index" json:"-"`
+	Kind      string    `gorm:"size:20;not null" json:"kind"`
+	Name      string    `gorm:"size:100" json:"name"`
+	URL       string    `gorm:"size:1000;not null" json:"url"`
+	Secret    string    `gorm:"size:200" json:"-"`
+	Enabled   bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// NotifyRule selects which new uploads notify the user, and where to.
+type NotifyRule struct {
+	ID              uint      `gorm:"primaryKey" json:"id"`
+	UserID          uint      `gorm:"not null;index" json:"-"`
+	Scope           string    `gorm:"size:20;not null;default:'all'" json:"scope"`
+	TagID           int64     `gorm:"default:0" json:"tagid"`           // for scope "tag"
+	Keywords        string    `gorm:"size:500" json:"keywords"`         // comma-separated, any must match the title; empty = all
+	ExcludeKeywords string    `gorm:"size:500" json:"exclude_keywords"` // comma-separated, none may match
+	ChannelIDs      string    `gorm:"size:200" json:"channel_ids"`      // comma-separated; empty = every enabled channel
+	Enabled         bool      `gorm:"default:true" json:"enabled"`
+	CreatedAt       time.Time `json:"-"`
+	UpdatedAt       time.Time `json:"-"`
+}
+
+// MatchTitle applies the keyword filters, case-insensitively.
+func (r *NotifyRule) MatchTitle(title string) bool {
+	title = strings.ToLower(title)
+	for _, k := range splitKeywords(r.ExcludeKeywords) {
+		if strings.Contains(title, k) {
+			return false
+		}
+	}
+	keywords := splitKeywords(r.Keywords)
+	if len(keywords) == 0 {
+		return true
+	}
+	for _, k := range keywords {
+		if strings.Contains(title, k) {
+			return true
+		}
+	}
+	return false
+}
+
+func splitKeywords(s string) []string {
+	var out []string
+	for _, k := range strings.Split(s, ",") {
+		if k = strings.ToLower(strings.TrimSpace(k)); k != "" {
+			out = append(out, k)
+		}
+	}
+	return out
+}
+
+// NotifyEvent is one new upload a user was notified about. It is created
+// once per user and video, and is what the SSE stream replays.
+type NotifyEvent struct {
+	ID        uint      `gorm:"primaryKey" json:"id"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_nevent_user_aid" json:"-"`
+	Aid       int64     `gorm:"not null;uniqueIndex:idx_nevent_user_aid" json:"aid"`
+	Bvid      string    `gorm:"size:20" json:"bvid"`
+	Mid       int64     `gorm:"not null" json:"mid"`
+	Uname     string    `gorm:"size:200" json:"uname"`
+	Title     string    `gorm:"size:500" json:"title"`
+	Pic       string    `gorm:"size:500" json:"pic"`
+	Duration  int       `json:"duration"`
+	Pubdate   int64     `json:"pubdate"`
+	RuleID    uint      `json:"rule_id"`
+	CreatedAt time.Time `json:"-"`
+}
+
+// ToBiliJSON converts to the notification payload sent to clients.
+func (e *NotifyEvent) ToBiliJSON() map[string]interface{} {
+	return map[string]interface{}{
+		"id":       e.ID,
+		"type":     "new_video",
+		"aid":      e.Aid,
+		"bvid":     e.Bvid,
+		"title":    e.Title,
+		"pic":      ImageURL(e.Pic),
+		"duration": e.Duration,
+		"pubdate":  e.Pubdate,
+		"owner": map[string]interface{}{
+			"mid":  e.Mid,
+			"name": e.Uname,
+		},
+		"created_at": e.CreatedAt.Unix(),
+	}
+}
+
+// NotifyDelivery tracks sending one event to one channel. The unique index
+// keeps an event from being queued twice; Attempts and NextAttemptAt drive
+// the retry backoff.
+type NotifyDelivery struct {
+	ID            uint      `gorm:"primaryKey" json:"id"`
+	UserID        uint      `gorm:"not null;index" json:"-"`
+	EventID       uint      `gorm:"not null;uniqueIndex:idx_ndeliv_event_chan" json:"event_id"`
+	ChannelID     uint      `gorm:"not null;uniqueIndex:idx_ndeliv_event_chan" json:"channel_id"`
+	Status        string    `gorm:"size:20;not null;index:idx_ndeliv_status_next" json:"status"`
+	Attempts      int       `gorm:"default:0" json:"attempts"`
+	NextAttemptAt int64     `gorm:"index:idx_ndeliv_status_next" json:"next_attempt_at"`
+	LastError     string    `gorm:"size:500" json:"last_error"`
+	SentAt        int64     `json:"sent_at"`
+	CreatedAt     time.Time `json:"-"`
+	UpdatedAt     time.Time `json:"-"`
+}
@@ -0,0 +1,27 @@
+package model
+
+import "time"
+
+// PlaybackSession is one continuous viewing of a video part, folded together
+// from consecutive heartbeats. WatchHistory only keeps the latest position;
+// sessions are append-only and keep every viewing, which is what watching
+// statistics are computed from.
+type PlaybackSession struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;index:idx_psess_user_start;index:idx_psess_user_aid" json:"-"`
+	Business  string    `gorm:"size:30" json:"business"`
+	Aid       int64     `gorm:"not null;index:idx_psess_user_aid" json:"aid"`
+	Cid       int64     `json:"cid"`
+	Epid      int64     `json:"epid"`
+	AuthorMid int64     `json:"author_mid"`
+	StartAt   int64     `gorm:"not null;index:idx_psess_user_start" json:"start_at"`
+	EndAt     int64     `gorm:"not null" json:"end_at"`
+	StartPos  int       `json:"start_pos"` // seconds
+	EndPos    int       `json:"end_pos"`
+	Watched   int       `gorm:"default:0" json:"watched"` // seconds actually spent watching
+	Duration  int       `json:"duration"`
+	Ranges    string    `gorm:"type:text" json:"-"` // positions covered, "from-to,from-to" in seconds
+	Device    string    `gorm:"size:100" json:"device"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
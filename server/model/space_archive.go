@@ -0,0 +1,78 @@
+package model
+
+import (
+	"fmt"
+	"time"
+)
+
+// SpaceArchive is a video of an UP, as seen by the background crawl of
+// followed UPs. Rows accumulate across crawls, so the stored list grows past
+// the few newest videos fetched each time.
+type SpaceArchive struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	Mid       int64     `gorm:"not null;uniqueIndex:idx_space_mid_aid;index:idx_space_mid_pubdate" json:"mid"`
+	Aid       int64     `gorm:"not null;uniqueIndex:idx_space_mid_aid" json:"aid"`
+	Bvid      string    `gorm:"size:20" json:"bvid"`
+	Title     string    `gorm:"size:500" json:"title"`
+	Pic       string    `gorm:"size:500" json:"pic"`
+	Duration  int       `json:"duration"`
+	Pubdate   int64     `gorm:"index:idx_space_mid_pubdate" json:"pubdate"`
+	Play      int64     `json:"play"`
+	Danmaku   int64     `json:"danmaku"`
+	FetchedAt int64     `json:"fetched_at"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
+// formatLength renders seconds as the "mm:ss" / "h:mm:ss" the space APIs use.
+func formatLength(seconds int) string {
+	if seconds >= 3600 {
+		return fmt.Sprintf("%d:%02d:%02d", seconds/3600, seconds%3600/60, seconds%60)
+	}
+	return fmt.Sprintf("%02d:%02d", seconds/60, seconds%60)
+}
+
+// ToSearchArchiveJSON converts to a /x/space/wbi/arc/search vlist item.
+func (a *SpaceArchive) ToSearchArchiveJSON(author string) map[string]interface{} {
+	return map[string]interface{}{
+		"aid":          a.Aid,
+		"bvid":         a.Bvid,
+		"title":        a.Title,
+		"pic":          ImageURL(a.Pic),
+		"description":  "",
+		"subtitle":     "",
+		"length":       formatLength(a.Duration),
+		"created":      a.Pubdate,
+		"play":         a.Play,
+		"video_review": a.Danmaku,
+		"comment":      0,
+		"typeid":       0,
+		"copyright":    "1",
+		"review":       0,
+		"mid":          a.Mid,
+		"author":       author,
+		"hide_click":   false,
+	}
+}
+
+// ToSpaceCursorJSON converts to an app /x/v2/space/archive/cursor item.
+func (a *SpaceArchive) ToSpaceCursorJSON(author string) map[string]interface{} {
+	return map[string]interface{}{
+		"title":    a.Title,
+		"subtitle": "",
+		"tname":    "",
+		"cover":    ImageURL(a.Pic),
+		"uri":      fmt.Sprintf("bilibili://video/%d", a.Aid),
+		"param":    fmt.Sprintf("%d", a.Aid),
+		"goto":     "av",
+		"length":   formatLength(a.Duration),
+		"duration": a.Duration,
+		"ctime":    a.Pubdate,
+		"bvid":     a.Bvid,
+		"play":     a.Play,
+		"danmaku":  a.Danmaku,
+		"author":   author,
+		"state":    true,
+		"videos":   1,
+	}
+}
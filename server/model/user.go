@@ -6,6 +6,7 @@ type User struct {
 	ID        uint      `gorm:"primaryKey" json:"id"`
 	Username  string    `gorm:"uniqueIndex;size:64;not null" json:"username"`
 	Password  string    `gorm:"size:255;not null" json:"-"`
+	FeedToken string    `gorm:"size:32;index" json:"-"` // secret for feed reader URLs, see /feed/:token
 	CreatedAt time.Time `json:"created_at"`
 	UpdatedAt time.Time `json:"updated_at"`
 }
@@ -1,15 +1,22 @@
 package model
 
-import "time"
+import (
+	"strconv"
+	"strings"
+	"time"
+)
 
 type WatchHistory struct {
 	ID         uint      `gorm:"primaryKey" json:"-"`
-	UserID     uint      `gorm:"not null;uniqueIndex:idx_hist_user_aid" json:"-"`
-	Aid        int64     `gorm:"not null;uniqueIndex:idx_hist_user_aid" json:"aid"`
+	UserID     uint      `gorm:"not null;uniqueIndex:idx_hist_user_biz_oid" json:"-"`
+	Business   string    `gorm:"size:30;uniqueIndex:idx_hist_user_biz_oid" json:"business"` // archive/pgc/cheese/live/article
+	Aid        int64     `gorm:"not null;uniqueIndex:idx_hist_user_biz_oid" json:"aid"`     // oid: aid, live room id or article cvid
 	Bvid       string    `gorm:"size:20" json:"bvid"`
 	Cid        int64     `json:"cid"`
+	Page       int       `gorm:"default:1" json:"page"`
+	Part       string    `gorm:"size:200" json:"part"`
 	Epid       int64     `json:"epid"`
-	SeasonID   int64     `json:"season_id"`
+	SeasonID   int64     `gorm:"index:idx_hist_season" json:"season_id"`
 	Title      string    `gorm:"size:500" json:"title"`
 	LongTitle  string    `gorm:"size:500" json:"long_title"`
 	Cover      string    `gorm:"size:500" json:"cover"`
@@ -19,37 +26,49 @@ type WatchHistory struct {
 	AuthorName string    `gorm:"size:100" json:"author_name"`
 	AuthorFace string    `gorm:"size:500" json:"author_face"`
 	Badge      string    `gorm:"size:50" json:"badge"`
-	Kid        string    `gorm:"size:50" json:"kid"`       // business_oid format for deletion
-	Business   string    `gorm:"size:30" json:"business"`  // archive/pgc/live/article
+	Kid        string    `gorm:"size:50" json:"-"`         // business_oid, server-side only; the API exposes the numeric oid
 	ViewAt     int64     `gorm:"not null;index:idx_hist_view_at" json:"view_at"`
 	Videos     int       `json:"videos"`
 	Current    string    `gorm:"size:200" json:"current"`
 	IsFinish   int       `gorm:"default:0" json:"is_finish"`
 	IsFav      int       `gorm:"default:0" json:"is_fav"`
+	LiveStatus int       `gorm:"default:0" json:"live_status"`
+	Covers     string    `gorm:"size:2000" json:"covers"` // comma-separated, article only
+	URI        string    `gorm:"size:500" json:"uri"`
+	TagName    string    `gorm:"size:100" json:"tag_name"`
+	Invalid    bool      `gorm:"default:false" json:"invalid"` // video deleted upstream, see LostResource
 	CreatedAt  time.Time `json:"-"`
 	UpdatedAt  time.Time `json:"-"`
 }
 
 // ToBiliJSON converts to Bilibili-compatible history list item JSON.
 func (h *WatchHistory) ToBiliJSON() map[string]interface{} {
+	var covers []string
+	if h.Covers != "" {
+		covers = strings.Split(h.Covers, ",")
+	}
+	page := h.Page
+	if page == 0 {
+		page = 1
+	}
 	return map[string]interface{}{
 		"title":       h.Title,
 		"long_title":  h.LongTitle,
-		"cover":       h.Cover,
-		"covers":      nil,
-		"uri":         "",
+		"cover":       ImageURL(h.Cover),
+		"covers":      imageURLs(covers),
+		"uri":         h.URI,
 		"history": map[string]interface{}{
 			"oid":      h.Aid,
 			"epid":     h.Epid,
 			"bvid":     h.Bvid,
-			"page":     1,
+			"page":     page,
 			"cid":      h.Cid,
-			"part":     "",
+			"part":     h.Part,
 			"business": h.Business,
 		},
 		"videos":      h.Videos,
 		"author_name": h.AuthorName,
-		"author_face": h.AuthorFace,
+		"author_face": ImageURL(h.AuthorFace),
 		"author_mid":  h.AuthorMid,
 		"view_at":     h.ViewAt,
 		"progress":    h.Progress,
@@ -61,13 +80,50 @@ func (h *WatchHistory) ToBiliJSON() map[string]interface{} {
 		"new_desc":    "",
 		"is_finish":   h.IsFinish,
 		"is_fav":      h.IsFav,
-		"kid":         h.Aid, // used for deletion
-		"tag_name":    "",
-		"live_status": 0,
+		"kid":         h.Aid, // numeric oid; clients send business_kid to DelHistory
+		"tag_name":    h.TagName,
+		"live_status": h.LiveStatus,
+		"attr":        invalidAttr(h.Invalid),
 	}
 }
 
+// HistoryKid builds the "business_oid" kid clients send back to delete an
+// entry, e.g. archive_170001 or live_21452505.
+func HistoryKid(business string, oid int64) string {
+	return business + "_" + strconv.FormatInt(oid, 10)
+}
+
+// WatchHistoryPart records the resume point of one part (cid) of a video, or
+// one episode of a bangumi/course season. The history list shows a single
+// WatchHistory per video or season; these rows sit underneath it so switching
+// parts doesn't lose the progress of the others.
+type WatchHistoryPart struct {
+	ID        uint      `gorm:"primaryKey" json:"-"`
+	UserID    uint      `gorm:"not null;uniqueIndex:idx_histpart_user_aid_cid" json:"-"`
+	HistoryID uint      `gorm:"not null;index" json:"-"`
+	Aid       int64     `gorm:"not null;uniqueIndex:idx_histpart_user_aid_cid" json:"aid"`
+	Cid       int64     `gorm:"not null;uniqueIndex:idx_histpart_user_aid_cid" json:"cid"`
+	Epid      int64     `json:"epid"`
+	Page      int       `gorm:"default:1" json:"page"`
+	Part      string    `gorm:"size:200" json:"part"`
+	Progress  int       `gorm:"default:0" json:"progress"` // seconds, -1 = finished
+	Duration  int       `json:"duration"`
+	ViewAt    int64     `gorm:"not null" json:"view_at"`
+	CreatedAt time.Time `json:"-"`
+	UpdatedAt time.Time `json:"-"`
+}
+
 type UserSettings struct {
 	UserID        uint `gorm:"primaryKey" json:"-"`
 	HistoryPaused int  `gorm:"default:0" json:"history_paused"` // 0: recording, 1: paused
+
+	// History retention; 0 disables each rule
+	HistoryKeepDays    int `gorm:"default:0" json:"history_keep_days"`    // drop entries not viewed for N days
+	HistoryKeepEntries int `gorm:"default:0" json:"history_keep_entries"` // keep only the N most recent entries
+	HistoryMinProgress int `gorm:"default:0" json:"history_min_progress"` // drop videos watched for under N seconds
+
+	// Watch later maintenance; 0 disables each rule
+	ToviewAutoRemove int `gorm:"default:0" json:"toview_auto_remove"` // 1: remove videos once finished
+	ToviewExpireDays int `gorm:"default:0" json:"toview_expire_days"` // drop videos added more than N days ago
+	ToviewMaxSize    int `gorm:"default:0" json:"toview_max_size"`    // keep at most N videos, evicting the oldest
 }
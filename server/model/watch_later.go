@@ -18,7 +18,9 @@ type WatchLater struct {
 	Pubdate   int64     `json:"pubdate"`
 	Progress  int       `gorm:"default:0" json:"progress"`
 	Viewed    int       `gorm:"default:0" json:"viewed"` // 0: unwatched, 1: watched
+	SortOrder int       `gorm:"default:0" json:"sort_order"` // manual order, higher first; 0 = never arranged
 	AddedAt   int64     `gorm:"not null" json:"added_at"`
+	Invalid   bool      `gorm:"default:false" json:"invalid"` // video deleted upstream, see LostResource
 	CreatedAt time.Time `json:"-"`
 	UpdatedAt time.Time `json:"-"`
 }
@@ -29,15 +31,16 @@ func (w *WatchLater) ToMediaListJSON() map[string]interface{} {
 	return map[string]interface{}{
 		"id":       w.Aid,
 		"title":    w.Title,
-		"cover":    w.Pic,
+		"cover":    ImageURL(w.Pic),
 		"duration": w.Duration,
 		"pubtime":  w.Pubdate,
 		"bv_id":    w.Bvid,
 		"type":     2, // ugc
+		"attr":     invalidAttr(w.Invalid),
 		"upper": map[string]interface{}{
 			"mid":  w.OwnerMid,
 			"name": w.OwnerName,
-			"face": w.OwnerFace,
+			"face": ImageURL(w.OwnerFace),
 		},
 		"cnt_info": map[string]interface{}{
 			"play":    0,
@@ -55,7 +58,7 @@ func (w *WatchLater) ToBiliJSON() map[string]interface{} {
 		"aid":      w.Aid,
 		"bvid":     w.Bvid,
 		"title":    w.Title,
-		"pic":      w.Pic,
+		"pic":      ImageURL(w.Pic),
 		"duration": w.Duration,
 		"pubdate":  w.Pubdate,
 		"cid":      w.Cid,
@@ -64,7 +67,7 @@ func (w *WatchLater) ToBiliJSON() map[string]interface{} {
 		"owner": map[string]interface{}{
 			"mid":  w.OwnerMid,
 			"name": w.OwnerName,
-			"face": w.OwnerFace,
+			"face": ImageURL(w.OwnerFace),
 		},
 		"stat": map[string]interface{}{
 			"aid":    w.Aid,
@@ -79,5 +82,6 @@ func (w *WatchLater) ToBiliJSON() map[string]interface{} {
 		"is_pugv":     false,
 		"season_id":   0,
 		"redirect_url": "",
+		"attr":        invalidAttr(w.Invalid),
 	}
 }
@@ -0,0 +1,75 @@
+package notify
+
+import "sync"
+
+// Event is a notification pushed to a user's open streams.
+type Event struct {
+	ID   uint
+	Data []byte // JSON
+}
+
+// streamBuffer is how many events a slow stream may fall behind. Past that,
+// new events are not queued for it; the stream is flagged as lagging and has
+// to catch up by replaying from the database.
+const streamBuffer = 32
+
+// Stream is one open connection's view of a user's events.
+type Stream struct {
+	// Events carries new events in id order while the stream keeps up.
+	Events <-chan Event
+	// Lagged is signalled when an event was dropped because Events was full.
+	// Events queued after the drop are not contiguous; replay id > last sent
+	// before writing any of them.
+	Lagged <-chan struct{}
+
+	events chan Event
+	lagged chan struct{}
+}
+
+var (
+	streamsMu sync.Mutex
+	streams   = make(map[uint]map[*Stream]struct{})
+)
+
+// Subscribe opens a stream of the user's events. Call cancel when the
+// connection closes.
+func Subscribe(userID uint) (s *Stream, cancel func()) {
+	s = &Stream{
+		events: make(chan Event, streamBuffer),
+		lagged: make(chan struct{}, 1),
+	}
+	s.Events, s.Lagged = s.events, s.lagged
+
+	streamsMu.Lock()
+	if streams[userID] == nil {
+		streams[userID] = make(map[*Stream]struct{})
+	}
+	streams[userID][s] = struct{}{}
+	streamsMu.Unlock()
+
+	return s, func() {
+		streamsMu.Lock()
+		delete(streams[userID], s)
+		if len(streams[userID]) == 0 {
+			delete(streams, userID)
+		}
+		streamsMu.Unlock()
+	}
+}
+
+// Publish sends an event to every open stream of the user without blocking.
+// Streams too far behind are flagged as lagging instead.
+func Publish(userID uint, e Event) {
+	streamsMu.Lock()
+	defer streamsMu.Unlock()
+	for s := range streams[userID] {
+		select {
+		case s.events <- e:
+		default:
+			select {
+			case s.lagged <- struct{}{}:
+			default:
+			}
+		}
+	}
+}
@@ -0,0 +1,36 @@
+package notify
+
+import "testing"
+
+func TestPublishFlagsLaggingStream(t *testing.T) {
+	s, cancel := Subscribe(1)
+	defer cancel()
+
+	for i := 1; i <= streamBuffer; i++ {
+		Publish(1, Event{ID: uint(i)})
+	}
+	select {
+	case <-s.Lagged:
+		t.Fatal("stream flagged as lagging before its buffer was full")
+	default:
+	}
+
+	Publish(1, Event{ID: streamBuffer + 1})
+	select {
+	case <-s.Lagged:
+	default:
+		t.Fatal("dropped event did not flag the stream as lagging")
+	}
+	if got := len(s.Events); got != streamBuffer {
+		t.Errorf("queued %d events, want %d", got, streamBuffer)
+	}
+}
+
+func TestCancelStopsDelivery(t *testing.T) {
+	s, cancel := Subscribe(2)
+	cancel()
+	Publish(2, Event{ID: 1})
+	if len(s.Events) != 0 {
+		t.Error("event delivered after cancel")
+	}
+}
@@ -0,0 +1,226 @@
+// Package notify delivers new-upload notifications. Push channels (HTTP
+// webhook, ntfy, Gotify) are pluggable senders picked by kind; clients that
+// keep a connection open are served from the in-process Hub instead.
+package notify
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"syscall"
+	"time"
+)
+
+// Message is a notification, independent of the channel it goes to.
+type Message struct {
+	ID      uint // delivery id; lets receivers drop duplicates of a retry
+	Title   string
+	Body    string
+	URL     string // what tapping the notification opens
+	Image   string
+	Payload interface{} // full event, sent as JSON by the webhook
+}
+
+// Target is where a message is sent.
+type Target struct {
+	URL    string
+	Secret string
+}
+
+// Sender posts a message to one kind of channel.
+type Sender interface {
+	Send(t Target, m *Message) error
+}
+
+// SenderFunc adapts a function to Sender.
+type SenderFunc func(t Target, m *Message) error
+
+func (f SenderFunc) Send(t Target, m *Message) error { return f(t, m) }
+
+var senders = map[string]Sender{
+	"webhook": SenderFunc(sendWebhook),
+	"ntfy":    SenderFunc(sendNtfy),
+	"gotify":  SenderFunc(sendGotify),
+}
+
+// ErrUnknownKind is returned for channel kinds without a sender.
+var ErrUnknownKind = errors.New("notify: unknown channel kind")
+
+// Register adds or replaces the sender for a channel kind.
+func Register(kind string, s Sender) {
+	senders[kind] = s
+}
+
+// Supported reports whether kind has a sender.
+func Supported(kind string) bool {
+	_, ok := senders[kind]
+	return ok
+}
+
+// Send delivers m through the sender for kind.
+func Send(kind string, t Target, m *Message) error {
+	s, ok := senders[kind]
+	if !ok {
+		return ErrUnknownKind
+	}
+	return s.Send(t, m)
+}
+
+// ErrPrivateTarget is returned for channel URLs that resolve to loopback,
+// private or link-local addresses. Channel URLs are user input, so the server
+// must not become a way to reach its own network.
+var ErrPrivateTarget = errors.New("notify: target address not allowed")
+
+// client refuses to connect to non-public addresses. The check runs on the
+// address actually dialed, after DNS resolution and on every redirect, so a
+// hostname can't be re-pointed at an internal host after CheckURL passed.
+var client = &http.Client{
+	Timeout: 15 * time.Second,
+	Transport: &http.Transport{
+		DialContext: (&net.Dialer{
+			Timeout: 10 * time.Second,
+			Control: func(network, address string, _ syscall.RawConn) error {
+				host, _, err := net.SplitHostPort(address)
+				if err != nil {
+					return err
+				}
+				if ip := net.ParseIP(host); ip == nil || !publicIP(ip) {
+					return ErrPrivateTarget
+				}
+				return nil
+			},
+		}).DialContext,
+		TLSHandshakeTimeout: 10 * time.Second,
+	},
+}
+
+// CheckURL validates a channel URL before it is stored: http(s) only, and
+// every address the host resolves to must be public.
+func CheckURL(rawURL string) error {
+	u, err := url.Parse(rawURL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Hostname() == "" {
+		return fmt.Errorf("notify: invalid url %q", rawURL)
+	}
+	ips, err := net.LookupIP(u.Hostname())
+	if err != nil {
+		return fmt.Errorf("notify: resolve %s: %w", u.Hostname(), err)
+	}
+	for _, ip := range ips {
+		if !publicIP(ip) {
+			return ErrPrivateTarget
+		}
+	}
+	return nil
+}
+
+// cgnat is the shared address space of RFC 6598, not covered by IsPrivate.
+var cgnat = &net.IPNet{IP: net.IPv4(100, 64, 0, 0), Mask: net.CIDRMask(10, 32)}
+
+func publicIP(ip net.IP) bool {
+	return ip.IsGlobalUnicast() && !ip.IsPrivate() && !cgnat.Contains(ip)
+}
+
+// post sends req and reports non-2xx answers by status only; the response
+// body is never surfaced to the channel owner.
+func post(req *http.Request) error {
+	resp, err := client.Do(req)
+	if err != nil {
+		if errors.Is(err, ErrPrivateTarget) {
+			return ErrPrivateTarget
+		}
+		return fmt.Errorf("notify: request to %s failed", req.URL.Host)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, io.LimitReader(resp.Body, 4096))
+	if resp.StatusCode < 200 || resp.StatusCode >= 300 {
+		return fmt.Errorf("notify: %s", resp.Status)
+	}
+	return nil
+}
+
+// sendWebhook POSTs the event as JSON. With a secret, the body is signed:
+// X-Signature-256 is "sha256=" + hex HMAC-SHA256(secret, body).
+func sendWebhook(t Target, m *Message) error {
+	body, err := json.Marshal(m.Payload)
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", t.URL, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Delivery-ID", fmt.Sprint(m.ID))
+	if t.Secret != "" {
+		mac := hmac.New(sha256.New, []byte(t.Secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+	return post(req)
+}
+
+// sendNtfy publishes to an ntfy topic URL, e.g. https://ntfy.sh/my-topic.
+func sendNtfy(t Target, m *Message) error {
+	req, err := http.NewRequest("POST", t.URL, strings.NewReader(m.Body))
+	if err != nil {
+		return err
+	}
+	// Header values must stay ASCII-safe; ntfy decodes RFC 2047 words
+	req.Header.Set("Title", "=?UTF-8?B?"+base64String(m.Title)+"?=")
+	if m.URL != "" {
+		req.Header.Set("Click", m.URL)
+	}
+	if m.Image != "" {
+		req.Header.Set("Attach", m.Image)
+	}
+	if t.Secret != "" {
+		req.Header.Set("Authorization", "Bearer "+t.Secret)
+	}
+	return post(req)
+}
+
+func base64String(s string) string {
+	return base64.StdEncoding.EncodeToString([]byte(s))
+}
+
+// sendGotify posts to a Gotify server's /message endpoint. The URL may be
+// the server root or the full message URL; the app token is the secret.
+func sendGotify(t Target, m *Message) error {
+	url := t.URL
+	if !strings.Contains(url, "/message") {
+		url = strings.TrimRight(url, "/") + "/message"
+	}
+	body, err := json.Marshal(map[string]interface{}{
+		"title":    m.Title,
+		"message":  m.Body,
+		"priority": 5,
+		"extras": map[string]interface{}{
+			"client::notification": map[string]interface{}{
+				"click":       map[string]string{"url": m.URL},
+				"bigImageUrl": m.Image,
+			},
+		},
+	})
+	if err != nil {
+		return err
+	}
+	req, err := http.NewRequest("POST", url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	if t.Secret != "" {
+		req.Header.Set("X-Gotify-Key", t.Secret)
+	}
+	return post(req)
+}
@@ -0,0 +1,115 @@
+package notify
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+)
+
+func TestPublicIP(t *testing.T) {
+	tests := []struct {
+		ip   string
+		want bool
+	}{
+		{"1.1.1.1", true},
+		{"2606:4700:4700::1111", true},
+		{"127.0.0.1", false},
+		{"::1", false},
+		{"10.1.2.3", false},
+		{"172.16.0.1", false},
+		{"192.168.1.1", false},
+		{"169.254.169.254", false},
+		{"fe80::1", false},
+		{"fd00::1", false},
+		{"100.64.0.1", false},
+		{"0.0.0.0", false},
+		{"224.0.0.1", false},
+		{"::ffff:127.0.0.1", false},
+	}
+	for _, tt := range tests {
+		if got := publicIP(net.ParseIP(tt.ip)); got != tt.want {
+			t.Errorf("publicIP(%s) = %v, want %v", tt.ip, got, tt.want)
+		}
+	}
+}
+
+func TestCheckURL(t *testing.T) {
+	tests := []struct {
+		url     string
+		private bool
+		invalid bool
+	}{
+		{"http://127.0.0.1:8080/hook", true, false},
+		{"http://[::1]/hook", true, false},
+		{"https://10.0.0.5/message", true, false},
+		{"http://169.254.169.254/latest/meta-data", true, false},
+		{"http://localhost/hook", true, false},
+		{"ftp://1.1.1.1/hook", false, true},
+		{"http:///hook", false, true},
+		{"https://1.1.1.1/hook", false, false},
+	}
+	for _, tt := range tests {
+		err := CheckURL(tt.url)
+		switch {
+		case tt.private && !errors.Is(err, ErrPrivateTarget):
+			t.Errorf("CheckURL(%q) = %v, want ErrPrivateTarget", tt.url, err)
+		case tt.invalid && (err == nil || errors.Is(err, ErrPrivateTarget)):
+			t.Errorf("CheckURL(%q) = %v, want an invalid url error", tt.url, err)
+		case !tt.private && !tt.invalid && err != nil:
+			t.Errorf("CheckURL(%q) = %v, want nil", tt.url, err)
+		}
+	}
+}
+
+func TestPostRefusesLoopback(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		t.Error("request reached a loopback server")
+	}))
+	defer srv.Close()
+
+	req, _ := http.NewRequest("POST", srv.URL, nil)
+	if err := post(req); !errors.Is(err, ErrPrivateTarget) {
+		t.Errorf("post to %s = %v, want ErrPrivateTarget", srv.URL, err)
+	}
+}
+
+func TestWebhookSignature(t *testing.T) {
+	var body []byte
+	var header http.Header
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ = io.ReadAll(r.Body)
+		header = r.Header
+		w.WriteHeader(http.StatusInternalServerError)
+		io.WriteString(w, "upstream secret")
+	}))
+	defer srv.Close()
+
+	// The test server is on loopback, which the real client refuses
+	defer func(c *http.Client) { client = c }(client)
+	client = srv.Client()
+
+	err := sendWebhook(Target{URL: srv.URL, Secret: "s3cret"}, &Message{ID: 7, Payload: map[string]int{"aid": 1}})
+	if err == nil || err.Error() != "notify: 500 Internal Server Error" {
+		t.Errorf("sendWebhook error = %v, want the status without the body", err)
+	}
+
+	mac := hmac.New(sha256.New, []byte("s3cret"))
+	mac.Write(body)
+	if want := "sha256=" + hex.EncodeToString(mac.Sum(nil)); header.Get("X-Signature-256") != want {
+		t.Errorf("X-Signature-256 = %q, want %q", header.Get("X-Signature-256"), want)
+	}
+	if header.Get("X-Delivery-ID") != "7" {
+		t.Errorf("X-Delivery-ID = %q, want 7", header.Get("X-Delivery-ID"))
+	}
+
+	sendWebhook(Target{URL: srv.URL}, &Message{Payload: map[string]int{}})
+	if header.Get("X-Signature-256") != "" {
+		t.Errorf("unsigned webhook sent X-Signature-256 %q", header.Get("X-Signature-256"))
+	}
+}
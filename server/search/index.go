@@ -0,0 +1,354 @@
+// Package search implements a small in-memory inverted index over a user's
+// stored items (history, favorites, watch later). Text is indexed as
+// character unigrams and bigrams — the same scheme as MySQL's ngram parser —
+// so Chinese titles match without word segmentation. Fields can additionally
+// be indexed by the pinyin spelling and initials of their Han characters.
+package search
+
+import (
+	"sort"
+	"strings"
+	"unicode"
+
+	"github.com/mozillazg/go-pinyin"
+)
+
+// Field is one searchable piece of text on a document.
+type Field struct {
+	Name   string // reported back in matches, e.g. "title", "upper", "bvid"
+	Text   string
+	Weight int
+	Pinyin bool // also match by pinyin spelling and initials
+}
+
+// Doc is a document to index. Ref is opaque to the index and lets the caller
+// map hits back to its own items.
+type Doc struct {
+	Source string
+	Ref    int
+	Time   int64 // recency, used to break score ties
+	Fields []Field
+}
+
+// Match describes where the query matched within one field. Spans are
+// [start, end) rune offsets into the field's original text; pinyin matches
+// are mapped back onto the Han characters they spell.
+type Match struct {
+	Field string   `json:"field"`
+	Via   string   `json:"via"` // text, pinyin or initials
+	Spans [][2]int `json:"spans"`
+}
+
+// Hit is a matched document with its score and highlight spans.
+type Hit struct {
+	Doc     *Doc
+	Score   int
+	Matches []Match
+}
+
+// variant is the normalized text of a field, with a map from each rune back
+// to its offset in the original.
+type variant struct {
+	runes []rune
+	orig  []int
+}
+
+// pinyinToken is one character of a field spelled in pinyin: every toneless
+// reading of a Han character, or an ASCII letter/digit as itself.
+type pinyinToken struct {
+	orig     int
+	readings []string
+}
+
+type indexedDoc struct {
+	doc    *Doc
+	text   []variant       // per field
+	pinyin [][]pinyinToken // per field; nil unless the field has Han text
+}
+
+// Index is an immutable inverted index; build it with New.
+type Index struct {
+	docs     []indexedDoc
+	postings map[string][]int
+}
+
+var pinyinArgs = pinyin.Args{Style: pinyin.Normal, Heteronym: true}
+
+// New indexes docs.
+func New(docs []Doc) *Index {
+	idx := &Index{
+		docs:     make([]indexedDoc, len(docs)),
+		postings: make(map[string][]int),
+	}
+	for i := range docs {
+		d := &docs[i]
+		id := indexedDoc{
+			doc:    d,
+			text:   make([]variant, len(d.Fields)),
+			pinyin: make([][]pinyinToken, len(d.Fields)),
+		}
+		seen := map[string]bool{}
+		for fi, f := range d.Fields {
+			v := textVariant(f.Text)
+			id.text[fi] = v
+			if f.Pinyin {
+				id.pinyin[fi] = pinyinTokens(v.runes)
+			}
+			for _, g := range grams(v.runes) {
+				if !seen[g] {
+					seen[g] = true
+					idx.postings[g] = append(idx.postings[g], i)
+				}
+			}
+		}
+		idx.docs[i] = id
+	}
+	return idx
+}
+
+// Search returns the documents matching query, best first. If sources is
+// non-empty only documents from those sources are considered.
+func (idx *Index) Search(query string, sources ...string) []Hit {
+	q := normalize(query)
+	if len(strings.TrimSpace(string(q))) == 0 {
+		return nil
+	}
+
+	// Text matches come from the inverted index. Pinyin is matched per
+	// character (readings vary), so an all-letter query scans every document.
+	candidates := idx.candidates(q)
+	py := strings.ReplaceAll(string(q), " ", "")
+	if !isASCIILetters(py) {
+		py = ""
+	}
+
+	allowed := map[string]bool{}
+	for _, s := range sources {
+		allowed[s] = true
+	}
+
+	var hits []Hit
+	for i, d := range idx.docs {
+		if len(allowed) > 0 && !allowed[d.doc.Source] {
+			continue
+		}
+		if !candidates[i] && py == "" {
+			continue
+		}
+		hit := Hit{Doc: d.doc}
+		for fi, f := range d.doc.Fields {
+			via := "text"
+			var spans [][2]int
+			if candidates[i] {
+				spans = findText(d.text[fi], q)
+			}
+			if len(spans) == 0 && py != "" && d.pinyin[fi] != nil {
+				via = "pinyin"
+				spans = findPinyin(d.pinyin[fi], py)
+			}
+			if len(spans) == 0 {
+				continue
+			}
+			hit.Matches = append(hit.Matches, Match{Field: f.Name, Via: via, Spans: spans})
+			score := f.Weight
+			if via == "text" {
+				score *= 2
+			}
+			if spans[0][0] == 0 {
+				score++ // prefix match
+			}
+			hit.Score += score
+		}
+		if len(hit.Matches) > 0 {
+			hits = append(hits, hit)
+		}
+	}
+
+	sort.Slice(hits, func(i, j int) bool {
+		if hits[i].Score != hits[j].Score {
+			return hits[i].Score > hits[j].Score
+		}
+		return hits[i].Doc.Time > hits[j].Doc.Time
+	})
+	return hits
+}
+
+// candidates intersects the posting lists of the query's grams.
+func (idx *Index) candidates(q []rune) map[int]bool {
+	var result map[int]bool
+	for _, g := range queryGrams(q) {
+		next := map[int]bool{}
+		for _, id := range idx.postings[g] {
+			if result == nil || result[id] {
+				next[id] = true
+			}
+		}
+		result = next
+		if len(result) == 0 {
+			break
+		}
+	}
+	if result == nil {
+		result = map[int]bool{}
+	}
+	return result
+}
+
+// findText returns the spans of every non-overlapping occurrence of needle
+// in v.
+func findText(v variant, needle []rune) [][2]int {
+	var spans [][2]int
+	n := len(needle)
+	for i := 0; i+n <= len(v.runes); {
+		if runesEqual(v.runes[i:i+n], needle) {
+			spans = append(spans, [2]int{v.orig[i], v.orig[i+n-1] + 1})
+			i += n
+			continue
+		}
+		i++
+	}
+	return spans
+}
+
+// findPinyin returns the spans of the characters spelled by every
+// non-overlapping pinyin match of q.
+func findPinyin(tokens []pinyinToken, q string) [][2]int {
+	var spans [][2]int
+	for i := 0; i < len(tokens); {
+		if end := matchPinyin(tokens, i, q); end >= 0 {
+			spans = append(spans, [2]int{tokens[i].orig, tokens[end].orig + 1})
+			i = end + 1
+			continue
+		}
+		i++
+	}
+	return spans
+}
+
+// matchPinyin reports the index of the last token consumed when q is spelled
+// by tokens starting at i, or -1. Each character may be typed as any of its
+// full readings or just the initial letter, and the final syllable may be
+// incomplete — so "zhongguo", "zg", "zguo" and "zhongg" all match 中国.
+func matchPinyin(tokens []pinyinToken, i int, q string) int {
+	if i >= len(tokens) {
+		return -1
+	}
+	for _, r := range tokens[i].readings {
+		for _, form := range []string{r, r[:1]} {
+			switch {
+			case strings.HasPrefix(q, form):
+				if len(q) == len(form) {
+					return i
+				}
+				if end := matchPinyin(tokens, i+1, q[len(form):]); end >= 0 {
+					return end
+				}
+			case strings.HasPrefix(form, q):
+				return i
+			}
+		}
+	}
+	return -1
+}
+
+func textVariant(s string) variant {
+	runes := normalize(s)
+	orig := make([]int, len(runes))
+	for i := range orig {
+		orig[i] = i
+	}
+	return variant{runes: runes, orig: orig}
+}
+
+// pinyinTokens spells runes in toneless pinyin, keeping ASCII letters and
+// digits as-is so mixed titles like "b站" read as "bzhan". It returns nil if
+// there is no Han character to spell.
+func pinyinTokens(runes []rune) []pinyinToken {
+	var tokens []pinyinToken
+	hasHan := false
+	for i, r := range runes {
+		switch {
+		case unicode.Is(unicode.Han, r):
+			var readings []string
+			seen := map[string]bool{}
+			for _, p := range pinyin.SinglePinyin(r, pinyinArgs) {
+				p = strings.ReplaceAll(p, "ü", "v")
+				if p != "" && !seen[p] {
+					seen[p] = true
+					readings = append(readings, p)
+				}
+			}
+			if len(readings) > 0 {
+				hasHan = true
+				tokens = append(tokens, pinyinToken{orig: i, readings: readings})
+			}
+		case r < unicode.MaxASCII && (unicode.IsLetter(r) || unicode.IsDigit(r)):
+			tokens = append(tokens, pinyinToken{orig: i, readings: []string{string(r)}})
+		}
+	}
+	if !hasHan {
+		return nil
+	}
+	return tokens
+}
+
+// normalize folds case and full-width ASCII rune by rune, so offsets into the
+// result are offsets into the original.
+func normalize(s string) []rune {
+	runes := []rune(s)
+	for i, r := range runes {
+		if r >= 0xFF01 && r <= 0xFF5E {
+			r -= 0xFEE0
+		} else if r == 0x3000 {
+			r = ' '
+		}
+		runes[i] = unicode.ToLower(r)
+	}
+	return runes
+}
+
+// grams returns every unigram and bigram of runes.
+func grams(runes []rune) []string {
+	out := make([]string, 0, 2*len(runes))
+	for i := range runes {
+		out = append(out, string(runes[i]))
+		if i+1 < len(runes) {
+			out = append(out, string(runes[i:i+2]))
+		}
+	}
+	return out
+}
+
+// queryGrams returns the grams a match must contain: the bigrams of the
+// query, or its single rune.
+func queryGrams(q []rune) []string {
+	if len(q) == 1 {
+		return []string{string(q)}
+	}
+	out := make([]string, 0, len(q)-1)
+	for i := 0; i+1 < len(q); i++ {
+		out = append(out, string(q[i:i+2]))
+	}
+	return out
+}
+
+func isASCIILetters(s string) bool {
+	if s == "" {
+		return false
+	}
+	for _, r := range s {
+		if r > unicode.MaxASCII || !unicode.IsLetter(r) {
+			return false
+		}
+	}
+	return true
+}
+
+func runesEqual(a, b []rune) bool {
+	for i := range a {
+		if a[i] != b[i] {
+			return false
+		}
+	}
+	return true
+}